@@ -1,11 +1,13 @@
 package slic
 
 import (
+  "context"
   "image"
   _ "image/jpeg"
   "os"
-  "sync"
   "testing"
+
+  "github.com/kurige/SLIC/lab"
 )
 
 const C float64 = 2000.0 // Compactness
@@ -51,7 +53,7 @@ func BenchmarkImageToLab(b *testing.B) {
   }
 
   for n := 0; n < b.N; n++ {
-    s.lvec, s.avec, s.bvec = imageToLab(img)
+    lab.ImageToLab(img)
   }
 }
 
@@ -64,12 +66,7 @@ func BenchmarkLabeling(b *testing.B) {
 
   for n := 0; n < b.N; n++ {
     s.resetDistances()
-    var wg sync.WaitGroup
-    for n := range s.superpixels {
-      superpixel := s.superpixels[n]
-      go s.labelPixelsInSuperpixel(superpixel, &wg)
-    }
-    wg.Wait()
+    s.labelPixels(context.Background())
   }
 }
 
@@ -81,12 +78,7 @@ func BenchmarkCentroids(b *testing.B) {
 
     // Just run one dummy iteration
     s.resetDistances()
-    var wg sync.WaitGroup
-    for i := range s.superpixels {
-      superpixel := s.superpixels[i]
-      go s.labelPixelsInSuperpixel(superpixel, &wg)
-    }
-    wg.Wait()
+    s.labelPixels(context.Background())
   }
 
   for n := 0; n < b.N; n++ {
@@ -102,18 +94,30 @@ func BenchmarkConnect(b *testing.B) {
 
     // Just run one dummy iteration
     s.resetDistances()
-    var wg sync.WaitGroup
-    for i := range s.superpixels {
-      superpixel := s.superpixels[i]
-      go s.labelPixelsInSuperpixel(superpixel, &wg)
-    }
-    wg.Wait()
+    s.labelPixels(context.Background())
+  }
+
+  for n := 0; n < b.N; n++ {
+    _, new_labels, _ := s.enforceLabelConnectivity()
+    copy(s.Labels, new_labels)
   }
+}
+
+func BenchmarkRun64(b *testing.B) {
+  img := loadInputImage()
+  b.ReportAllocs()
+
+  for n := 0; n < b.N; n++ {
+    limg := lab.ImageToLab(img)
+    MakeSlicFromLab(&limg, C, S).Run(10)
+  }
+}
+
+func BenchmarkRun32(b *testing.B) {
+  img := loadInputImage()
+  b.ReportAllocs()
 
   for n := 0; n < b.N; n++ {
-    new_labels := s.enforceLabelConnectivity()
-    for i := 0; i < s.sz; i++ {
-      s.Labels[i] = new_labels[i]
-    }
+    MakeSlicFromLab(lab.ImageToLab32(img), C, S).Run(10)
   }
 }
@@ -0,0 +1,388 @@
+package main
+
+import (
+  "errors"
+  "fmt"
+  "image"
+  "io"
+  "io/fs"
+  "os"
+  "path/filepath"
+  "runtime"
+  "sort"
+  "strings"
+  "sync"
+  "time"
+
+  "github.com/kurige/SLIC"
+  "github.com/kurige/SLIC/labelio"
+)
+
+var imageExts = map[string]bool{
+  ".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
+  ".pgm": true, ".ppm": true, ".pnm": true, ".pam": true,
+}
+
+// batchFile is an input image and its path relative to the directory or glob
+// prefix it was found under, which is where its outputs go under -out. base is
+// that path without its extension, which output names are built from.
+type batchFile struct {
+  input string
+  rel   string
+  base  string
+}
+
+// collect expands directories and glob patterns into image files. Directories
+// are searched recursively, except for the output directory out, so that
+// earlier outputs are not taken for inputs.
+func collect(args []string, out string) ([]batchFile, error) {
+  var files []batchFile
+  seen := make(map[string]bool)
+  outAbs, err := filepath.Abs(out)
+  if err != nil {
+    return nil, err
+  }
+  inOut := func(path string) bool {
+    abs, err := filepath.Abs(path)
+    if err != nil {
+      return false
+    }
+    rel, err := filepath.Rel(outAbs, abs)
+    return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+  }
+  add := func(root, path string) {
+    if seen[path] || !imageExts[strings.ToLower(filepath.Ext(path))] || inOut(path) {
+      return
+    }
+    seen[path] = true
+    rel, err := filepath.Rel(root, path)
+    if err != nil {
+      rel = filepath.Base(path)
+    }
+    files = append(files, batchFile{path, rel, strings.TrimSuffix(rel, filepath.Ext(rel))})
+  }
+
+  for _, arg := range args {
+    matches, err := filepath.Glob(arg)
+    if err != nil {
+      return nil, err
+    }
+    if len(matches) == 0 {
+      return nil, fmt.Errorf("%s: no such file or directory", arg)
+    }
+    root := globRoot(arg)
+    for _, m := range matches {
+      info, err := os.Stat(m)
+      if err != nil {
+        return nil, err
+      }
+      if !info.IsDir() {
+        if root == m {
+          // A plain file name: its outputs go at the top of -out.
+          add(filepath.Dir(m), m)
+        } else {
+          add(root, m)
+        }
+        continue
+      }
+      err = filepath.WalkDir(m, func(path string, d fs.DirEntry, err error) error {
+        if err != nil {
+          return err
+        }
+        if d.IsDir() && inOut(path) {
+          return filepath.SkipDir
+        }
+        if !d.IsDir() {
+          add(root, path)
+        }
+        return nil
+      })
+      if err != nil {
+        return nil, err
+      }
+    }
+  }
+  sort.Slice(files, func(i, j int) bool { return files[i].input < files[j].input })
+  return files, disambiguate(files)
+}
+
+// disambiguate keeps the source extension in the output names of images that
+// would otherwise share them, such as a.jpg and a.png, which become a_jpg and
+// a_png. Names are compared ignoring case for case-insensitive file systems.
+func disambiguate(files []batchFile) error {
+  count := make(map[string]int)
+  for _, f := range files {
+    count[strings.ToLower(f.base)]++
+  }
+  for i := range files {
+    f := &files[i]
+    if count[strings.ToLower(f.base)] > 1 {
+      f.base += "_" + strings.TrimPrefix(filepath.Ext(f.rel), ".")
+    }
+  }
+  taken := make(map[string]string)
+  for _, f := range files {
+    key := strings.ToLower(f.base)
+    if other, ok := taken[key]; ok {
+      return fmt.Errorf("%s and %s would write the same outputs", other, f.input)
+    }
+    taken[key] = f.input
+  }
+  return nil
+}
+
+// globRoot returns the leading directories of pattern that contain no glob
+// metacharacters, or pattern itself if it has none.
+func globRoot(pattern string) string {
+  clean := filepath.Clean(pattern)
+  if !strings.ContainsAny(clean, `*?[\`) {
+    return clean
+  }
+  parts := strings.Split(clean, string(filepath.Separator))
+  for i, part := range parts {
+    if strings.ContainsAny(part, `*?[\`) {
+      root := strings.Join(parts[:i], string(filepath.Separator))
+      if root == "" && filepath.IsAbs(clean) {
+        return string(filepath.Separator)
+      }
+      if root == "" {
+        return "."
+      }
+      return root
+    }
+  }
+  return clean
+}
+
+type batchEntry struct {
+  Input       string  `json:"input"`
+  Status      string  `json:"status"`
+  Error       string  `json:"error,omitempty"`
+  Seconds     float64 `json:"seconds,omitempty"`
+  Superpixels int     `json:"superpixels,omitempty"`
+}
+
+type batchReport struct {
+  Started     time.Time    `json:"started"`
+  Seconds     float64      `json:"seconds"`
+  Workers     int          `json:"workers"`
+  Processed   int          `json:"processed"`
+  Skipped     int          `json:"skipped"`
+  Failed      int          `json:"failed"`
+  MeanSeconds float64      `json:"mean_seconds"`
+  Files       []batchEntry `json:"files"`
+}
+
+func (rep *batchReport) writeText(w io.Writer) error {
+  fmt.Fprintf(w, "%d processed, %d skipped, %d failed in %.1fs with %d workers",
+    rep.Processed, rep.Skipped, rep.Failed, rep.Seconds, rep.Workers)
+  if rep.Processed > 0 {
+    fmt.Fprintf(w, " (%.3fs per image)", rep.MeanSeconds)
+  }
+  fmt.Fprintln(w)
+  for _, e := range rep.Files {
+    if e.Status == "failed" {
+      fmt.Fprintf(w, "  %s: %s\n", e.Input, e.Error)
+    }
+  }
+  return nil
+}
+
+func runBatch(args []string) error {
+  fs := newFlagSet("batch", "dir|glob...")
+  s := addSettings(fs)
+  e := addEdgeFlags(fs)
+  out := fs.String("out", "", "output directory; the input tree is mirrored under it")
+  outputs := fs.String("outputs", "edges", "comma separated outputs per image: edges, average, labels, stats")
+  mode := fs.String("mode", "mean", "average mode: mean, median, random or dots")
+  labelFormat := fs.String("label-format", "png", "label format: png, pgm, npy, raw or seg")
+  workers := fs.Int("workers", runtime.NumCPU(), "number of images processed at once")
+  cpu := fs.Int("cpu", 0, "maximum number of cores shared by the workers (0 means all)")
+  force := fs.Bool("force", false, "process images whose outputs already exist")
+  report := fs.String("report", "", "write a JSON report to this file (defaults to <out>/report.json)")
+  fs.Parse(args)
+
+  if *out == "" {
+    return errors.New("-out is required")
+  }
+  if s.stats != "" {
+    return errors.New("-stats is per image in batch mode; use -outputs stats")
+  }
+  if fs.NArg() == 0 {
+    return errors.New("expected directories or glob patterns")
+  }
+  if *workers < 1 {
+    *workers = 1
+  }
+  opts, err := s.options()
+  if err != nil {
+    return err
+  }
+  // Check the per-image settings once rather than failing every image.
+  if _, err := e.options(); err != nil {
+    return err
+  }
+  switch *mode {
+  case "mean", "median", "random", "dots":
+  default:
+    return fmt.Errorf("unknown average mode %q", *mode)
+  }
+  if *cpu > 0 && *cpu < runtime.NumCPU() {
+    runtime.GOMAXPROCS(*cpu)
+  }
+  kinds, err := parseOutputs(*outputs, *labelFormat)
+  if err != nil {
+    return err
+  }
+  files, err := collect(fs.Args(), *out)
+  if err != nil {
+    return err
+  }
+
+  rep := batchReport{Started: time.Now(), Workers: *workers, Files: make([]batchEntry, len(files))}
+  jobs := make(chan int)
+  var wg sync.WaitGroup
+  for n := 0; n < *workers; n++ {
+    wg.Add(1)
+    go func() {
+      defer wg.Done()
+      for i := range jobs {
+        rep.Files[i] = processBatchFile(files[i], *out, kinds, *force, s, opts, e, *mode)
+      }
+    }()
+  }
+  for i := range files {
+    jobs <- i
+  }
+  close(jobs)
+  wg.Wait()
+
+  var total float64
+  for _, entry := range rep.Files {
+    switch entry.Status {
+    case "ok":
+      rep.Processed++
+      total += entry.Seconds
+    case "skipped":
+      rep.Skipped++
+    case "failed":
+      rep.Failed++
+    }
+  }
+  if rep.Processed > 0 {
+    rep.MeanSeconds = total / float64(rep.Processed)
+  }
+  rep.Seconds = time.Since(rep.Started).Seconds()
+
+  rep.writeText(os.Stderr)
+  if *report == "" {
+    *report = filepath.Join(*out, "report.json")
+  }
+  if err := os.MkdirAll(filepath.Dir(*report), 0755); err != nil {
+    return err
+  }
+  if err := writeFile(*report, func(w io.Writer) error { return writeJSON(w, rep) }); err != nil {
+    return err
+  }
+  if rep.Failed > 0 {
+    return fmt.Errorf("%d of %d images failed", rep.Failed, len(files))
+  }
+  return nil
+}
+
+// outputKind is one of the files batch writes per image.
+type outputKind struct {
+  name string
+  ext  string
+}
+
+func parseOutputs(list, labelFormat string) ([]outputKind, error) {
+  var kinds []outputKind
+  for _, name := range strings.Split(list, ",") {
+    switch name = strings.TrimSpace(name); name {
+    case "edges", "average":
+      kinds = append(kinds, outputKind{name, "png"})
+    case "labels":
+      if !labelio.IsFormat(labelFormat) {
+        return nil, fmt.Errorf("unknown label format %q", labelFormat)
+      }
+      kinds = append(kinds, outputKind{name, labelFormat})
+    case "stats":
+      kinds = append(kinds, outputKind{name, "json"})
+    default:
+      return nil, fmt.Errorf("unknown output %q", name)
+    }
+  }
+  return kinds, nil
+}
+
+func processBatchFile(f batchFile, out string, kinds []outputKind, force bool, s *settings, opts slic.Options, e *edgeFlags, mode string) batchEntry {
+  entry := batchEntry{Input: f.input}
+  base := filepath.Join(out, f.base)
+  paths := make([]string, len(kinds))
+  for i, k := range kinds {
+    paths[i] = base + "_" + k.name + "." + k.ext
+  }
+  if !force && upToDate(f.input, paths) {
+    entry.Status = "skipped"
+    return entry
+  }
+
+  start := time.Now()
+  err := func() error {
+    if err := os.MkdirAll(filepath.Dir(base), 0755); err != nil {
+      return err
+    }
+    r, err := segmentFile(f.input, s, opts)
+    if err != nil {
+      return err
+    }
+    entry.Superpixels = r.slic.LabelCount()
+    for i, k := range kinds {
+      var err error
+      switch k.name {
+      case "edges":
+        var img image.Image
+        if img, err = drawEdges(r, e); err == nil {
+          err = writeImage(paths[i], "", img)
+        }
+      case "average":
+        var img image.Image
+        if img, err = average(r, mode); err == nil {
+          err = writeImage(paths[i], "", img)
+        }
+      case "labels":
+        err = writeLabels(paths[i], "", r)
+      case "stats":
+        err = writeStats(paths[i], "", r.summarize())
+      }
+      if err != nil {
+        return err
+      }
+    }
+    return nil
+  }()
+  entry.Seconds = time.Since(start).Seconds()
+  if err != nil {
+    entry.Status = "failed"
+    entry.Error = err.Error()
+    return entry
+  }
+  entry.Status = "ok"
+  return entry
+}
+
+// upToDate reports whether every output exists and is newer than input.
+func upToDate(input string, outputs []string) bool {
+  in, err := os.Stat(input)
+  if err != nil {
+    return false
+  }
+  for _, path := range outputs {
+    info, err := os.Stat(path)
+    if err != nil || info.ModTime().Before(in.ModTime()) {
+      return false
+    }
+  }
+  return true
+}
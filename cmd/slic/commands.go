@@ -0,0 +1,228 @@
+package main
+
+import (
+  "errors"
+  "flag"
+  "fmt"
+  "image"
+  "image/color"
+  "os"
+
+  "github.com/kurige/SLIC"
+)
+
+func newFlagSet(name, args string) *flag.FlagSet {
+  fs := flag.NewFlagSet(name, flag.ExitOnError)
+  fs.Usage = func() {
+    fmt.Fprintf(os.Stderr, "Usage: slic %s [flags] %s\n\nFlags:\n", name, args)
+    fs.PrintDefaults()
+  }
+  return fs
+}
+
+type edgeFlags struct {
+  color     string
+  thickness int
+  placement string
+}
+
+func addEdgeFlags(fs *flag.FlagSet) *edgeFlags {
+  e := new(edgeFlags)
+  fs.StringVar(&e.color, "color", "#000", "boundary color as #rgb, #rrggbb or #rrggbbaa")
+  fs.IntVar(&e.thickness, "thickness", 1, "boundary thickness in pixels")
+  fs.StringVar(&e.placement, "placement", "thin", "boundary placement: thin, inner or outer")
+  return e
+}
+
+var placements = map[string]slic.EdgePlacement{"thin": slic.EdgeThin, "inner": slic.EdgeInner, "outer": slic.EdgeOuter}
+
+func (e *edgeFlags) options() (*slic.DrawOptions, error) {
+  c, err := parseColor(e.color)
+  if err != nil {
+    return nil, err
+  }
+  placement, ok := placements[e.placement]
+  if !ok {
+    return nil, fmt.Errorf("unknown placement %q", e.placement)
+  }
+  return &slic.DrawOptions{Color: c, Thickness: e.thickness, Placement: placement}, nil
+}
+
+func drawEdges(r *result, e *edgeFlags) (image.Image, error) {
+  opts, err := e.options()
+  if err != nil {
+    return nil, err
+  }
+  return r.slic.DrawEdges(r.img, opts), nil
+}
+
+func average(r *result, mode string) (image.Image, error) {
+  switch mode {
+  case "mean":
+    return r.slic.MeanColorImage(), nil
+  case "median":
+    return r.slic.MedianColorImage(), nil
+  case "random":
+    return r.slic.RandomColorImage(1), nil
+  case "dots":
+    return r.slic.CentroidDots(r.slic.MeanColorImage(), 1, color.RGBA{255, 0, 0, 255}), nil
+  }
+  return nil, fmt.Errorf("unknown average mode %q", mode)
+}
+
+// oneStdout checks that at most one of the output paths is standard output.
+func oneStdout(paths ...string) error {
+  n := 0
+  for _, p := range paths {
+    if p == stdio {
+      n++
+    }
+  }
+  if n > 1 {
+    return errors.New("only one output can go to standard output")
+  }
+  return nil
+}
+
+func runSegment(args []string) error {
+  fs := newFlagSet("segment", "image|-")
+  s := addSettings(fs)
+  e := addEdgeFlags(fs)
+  edges := fs.String("edges", "", "write boundaries drawn over the image to this file")
+  avg := fs.String("average", "", "write the average color image to this file")
+  mode := fs.String("mode", "mean", "average mode: mean, median, random or dots")
+  labels := fs.String("labels", "", "write the label map to this file (.png, .pgm, .npy, .raw or .seg)")
+  fs.Parse(args)
+  if *edges == "" && *avg == "" && *labels == "" && s.stats == "" {
+    return errors.New("nothing to write: give at least one of -edges, -average, -labels or -stats")
+  }
+  if err := oneStdout(*edges, *avg, *labels, s.stats); err != nil {
+    return err
+  }
+
+  r, err := segment(fs, s)
+  if err != nil {
+    return err
+  }
+  if *edges != "" {
+    img, err := drawEdges(r, e)
+    if err != nil {
+      return err
+    }
+    if err := writeImage(*edges, "", img); err != nil {
+      return err
+    }
+  }
+  if *avg != "" {
+    img, err := average(r, *mode)
+    if err != nil {
+      return err
+    }
+    if err := writeImage(*avg, "", img); err != nil {
+      return err
+    }
+  }
+  if *labels != "" {
+    if err := writeLabels(*labels, "", r); err != nil {
+      return err
+    }
+  }
+  return r.writeStats(s)
+}
+
+func runEdges(args []string) error {
+  fs := newFlagSet("edges", "image|-")
+  s := addSettings(fs)
+  e := addEdgeFlags(fs)
+  out := fs.String("o", "", "output file, or - for standard output (defaults to <image>_edges.png)")
+  format := fs.String("format", "", "output format: png, jpeg, gif, ppm or pam (defaults to the -o extension, or png)")
+  fs.Parse(args)
+  if *out == "" {
+    *out = defaultOutput(fs.Arg(0), "edges", "png")
+  }
+  if err := oneStdout(*out, s.stats); err != nil {
+    return err
+  }
+
+  r, err := segment(fs, s)
+  if err != nil {
+    return err
+  }
+  img, err := drawEdges(r, e)
+  if err != nil {
+    return err
+  }
+  if err := writeImage(*out, *format, img); err != nil {
+    return err
+  }
+  return r.writeStats(s)
+}
+
+func runAverage(args []string) error {
+  fs := newFlagSet("average", "image|-")
+  s := addSettings(fs)
+  mode := fs.String("mode", "mean", "fill with the mean, median or random color, or mean with centroid dots")
+  out := fs.String("o", "", "output file, or - for standard output (defaults to <image>_average.png)")
+  format := fs.String("format", "", "output format: png, jpeg, gif, ppm or pam (defaults to the -o extension, or png)")
+  fs.Parse(args)
+  if *out == "" {
+    *out = defaultOutput(fs.Arg(0), "average", "png")
+  }
+  if err := oneStdout(*out, s.stats); err != nil {
+    return err
+  }
+
+  r, err := segment(fs, s)
+  if err != nil {
+    return err
+  }
+  img, err := average(r, *mode)
+  if err != nil {
+    return err
+  }
+  if err := writeImage(*out, *format, img); err != nil {
+    return err
+  }
+  return r.writeStats(s)
+}
+
+func runLabels(args []string) error {
+  fs := newFlagSet("labels", "image|-")
+  s := addSettings(fs)
+  out := fs.String("o", "", "output file, or - for standard output (defaults to <image>_labels.png)")
+  format := fs.String("format", "", "label format: png, pgm, npy, raw or seg (defaults to the -o extension, or png)")
+  fs.Parse(args)
+  if *out == "" {
+    ext := *format
+    if ext == "" {
+      ext = "png"
+    }
+    *out = defaultOutput(fs.Arg(0), "labels", ext)
+  }
+  if err := oneStdout(*out, s.stats); err != nil {
+    return err
+  }
+
+  r, err := segment(fs, s)
+  if err != nil {
+    return err
+  }
+  if err := writeLabels(*out, *format, r); err != nil {
+    return err
+  }
+  return r.writeStats(s)
+}
+
+func runStats(args []string) error {
+  fs := newFlagSet("stats", "image|-")
+  s := addSettings(fs)
+  out := fs.String("o", "", "output file (defaults to standard output)")
+  format := fs.String("format", "", "text or json (defaults to the -o extension, or text)")
+  fs.Parse(args)
+
+  r, err := segment(fs, s)
+  if err != nil {
+    return err
+  }
+  return writeStats(*out, *format, r.summarize())
+}
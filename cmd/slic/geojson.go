@@ -0,0 +1,151 @@
+package main
+
+import (
+  "image"
+
+  "github.com/kurige/SLIC"
+)
+
+// polygonJSON is a superpixel outline in the plain JSON output. Rings are
+// lists of [x, y] pixel corners; the first is the outer boundary.
+type polygonJSON struct {
+  Label int        `json:"label"`
+  Rings [][][2]int `json:"rings"`
+}
+
+type polygonsJSON struct {
+  Width       int           `json:"width"`
+  Height      int           `json:"height"`
+  Superpixels int           `json:"superpixels"`
+  Polygons    []polygonJSON `json:"polygons"`
+}
+
+func polygonsDocument(r *result) polygonsJSON {
+  size := r.img.Bounds().Size()
+  doc := polygonsJSON{Width: size.X, Height: size.Y, Superpixels: r.slic.LabelCount()}
+  for _, p := range r.slic.Polygons() {
+    pj := polygonJSON{Label: p.Label}
+    for _, ring := range p.Rings {
+      pj.Rings = append(pj.Rings, coords(ring, false))
+    }
+    doc.Polygons = append(doc.Polygons, pj)
+  }
+  return doc
+}
+
+// GeoJSON types, with coordinates in pixels. Rings are closed and wound as
+// RFC 7946 asks when y is read as pointing up.
+type featureCollection struct {
+  Type     string    `json:"type"`
+  Features []feature `json:"features"`
+}
+
+type feature struct {
+  Type       string                 `json:"type"`
+  ID         int                    `json:"id"`
+  Properties map[string]int         `json:"properties"`
+  Geometry   map[string]interface{} `json:"geometry"`
+}
+
+func geoJSONDocument(r *result) featureCollection {
+  area := make([]int, r.slic.LabelCount())
+  for _, l := range r.slic.Labels {
+    if l >= 0 {
+      area[l]++
+    }
+  }
+  fc := featureCollection{Type: "FeatureCollection", Features: []feature{}}
+  for _, p := range r.slic.Polygons() {
+    if len(p.Rings) == 0 {
+      continue
+    }
+    fc.Features = append(fc.Features, feature{
+      Type:       "Feature",
+      ID:         p.Label,
+      Properties: map[string]int{"label": p.Label, "area": area[p.Label]},
+      Geometry:   geometry(p),
+    })
+  }
+  return fc
+}
+
+// geometry returns a Polygon, or a MultiPolygon when the superpixel has more
+// than one outer ring, with every hole placed in the outer ring around it.
+func geometry(p slic.Polygon) map[string]interface{} {
+  var outers, holes [][]image.Point
+  for _, ring := range p.Rings {
+    if signedArea(ring) > 0 {
+      outers = append(outers, ring)
+    } else {
+      holes = append(holes, ring)
+    }
+  }
+  polys := make([][][][2]int, len(outers))
+  for i, outer := range outers {
+    polys[i] = [][][2]int{coords(outer, true)}
+  }
+  for _, hole := range holes {
+    x, y := insidePoint(hole)
+    for i, outer := range outers {
+      if contains(outer, x, y) {
+        polys[i] = append(polys[i], coords(hole, true))
+        break
+      }
+    }
+  }
+  if len(polys) == 1 {
+    return map[string]interface{}{"type": "Polygon", "coordinates": polys[0]}
+  }
+  return map[string]interface{}{"type": "MultiPolygon", "coordinates": polys}
+}
+
+func coords(ring []image.Point, closed bool) [][2]int {
+  c := make([][2]int, 0, len(ring)+1)
+  for _, pt := range ring {
+    c = append(c, [2]int{pt.X, pt.Y})
+  }
+  if closed && len(ring) > 0 {
+    c = append(c, [2]int{ring[0].X, ring[0].Y})
+  }
+  return c
+}
+
+func signedArea(ring []image.Point) int {
+  a := 0
+  for i, p := range ring {
+    q := ring[(i+1)%len(ring)]
+    a += p.X*q.Y - q.X*p.Y
+  }
+  return a
+}
+
+// insidePoint returns the center of the superpixel's pixel alongside the
+// first edge of ring, which lies on the edge's right.
+func insidePoint(ring []image.Point) (x, y float64) {
+  p, q := ring[0], ring[1%len(ring)]
+  dx, dy := sign(q.X-p.X), sign(q.Y-p.Y)
+  return float64(p.X) + 0.5*float64(dx) - 0.5*float64(dy), float64(p.Y) + 0.5*float64(dy) + 0.5*float64(dx)
+}
+
+func sign(v int) int {
+  switch {
+  case v > 0:
+    return 1
+  case v < 0:
+    return -1
+  }
+  return 0
+}
+
+// contains reports whether (x, y) is inside ring by ray casting.
+func contains(ring []image.Point, x, y float64) bool {
+  in := false
+  for i, p := range ring {
+    q := ring[(i+1)%len(ring)]
+    px, py, qx, qy := float64(p.X), float64(p.Y), float64(q.X), float64(q.Y)
+    if (py > y) != (qy > y) && x < px+(y-py)*(qx-px)/(qy-py) {
+      in = !in
+    }
+  }
+  return in
+}
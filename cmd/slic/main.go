@@ -0,0 +1,85 @@
+// Command slic segments images into superpixels.
+//
+// Usage:
+//
+//	slic <command> [flags] image
+//	slic batch [flags] -out dir dir|glob...
+//	slic serve [flags]
+//
+// The commands are:
+//
+//	segment   write any combination of the outputs below in one run
+//	edges     draw superpixel boundaries over the image
+//	average   fill superpixels with their mean, median or a random color
+//	labels    write the label map
+//	stats     print a summary of the segmentation
+//	batch     process directories or glob patterns of images concurrently
+//	serve     segment uploaded images over HTTP
+//
+// An image argument of - reads the image from standard input, and an output
+// path of - writes to standard output, with the format taken from -format or
+// else PNG. Output for an image read from standard input goes to standard
+// output unless -o says otherwise, so commands can be used in pipelines:
+//
+//	convert photo.tif png:- | slic edges -pixels 500 - | display
+//
+// JPEG images are rotated or flipped upright according to their EXIF
+// orientation before segmenting, so outputs match what image viewers show.
+// Pass -orient=false to segment the stored pixels as they are.
+//
+// Run "slic <command> -h" for the flags of a command.
+package main
+
+import (
+  "fmt"
+  "os"
+)
+
+type command struct {
+  name    string
+  summary string
+  run     func(args []string) error
+}
+
+var commands = []command{
+  {"segment", "write any combination of outputs in one run", runSegment},
+  {"edges", "draw superpixel boundaries over the image", runEdges},
+  {"average", "fill superpixels with their mean, median or a random color", runAverage},
+  {"labels", "write the label map", runLabels},
+  {"stats", "print a summary of the segmentation", runStats},
+  {"batch", "process directories or glob patterns of images concurrently", runBatch},
+  {"serve", "segment uploaded images over HTTP", runServe},
+}
+
+func usage() {
+  fmt.Fprintln(os.Stderr, "Usage: slic <command> [flags] image\n       slic batch [flags] -out dir dir|glob...\n       slic serve [flags]")
+  fmt.Fprintln(os.Stderr, "\nCommands:")
+  for _, c := range commands {
+    fmt.Fprintf(os.Stderr, "  %-8s  %s\n", c.name, c.summary)
+  }
+  fmt.Fprintln(os.Stderr, "\nRun \"slic <command> -h\" for the flags of a command.")
+}
+
+func main() {
+  if len(os.Args) < 2 {
+    usage()
+    os.Exit(2)
+  }
+  name := os.Args[1]
+  if name == "-h" || name == "-help" || name == "--help" || name == "help" {
+    usage()
+    return
+  }
+  for _, c := range commands {
+    if c.name == name {
+      if err := c.run(os.Args[2:]); err != nil {
+        fmt.Fprintln(os.Stderr, "slic "+name+":", err)
+        os.Exit(1)
+      }
+      return
+    }
+  }
+  fmt.Fprintf(os.Stderr, "slic: unknown command %q\n\n", name)
+  usage()
+  os.Exit(2)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+  "context"
+  "errors"
+  "flag"
+  "fmt"
+  "image"
+  _ "image/gif"
+  _ "image/jpeg"
+  _ "image/png"
+  "io"
+  "os"
+  "time"
+
+  "github.com/kurige/SLIC"
+  "github.com/kurige/SLIC/colorspace"
+  "github.com/kurige/SLIC/exif"
+  _ "github.com/kurige/SLIC/netpbm"
+)
+
+// settings are the segmentation flags shared by every command.
+type settings struct {
+  pixels      int
+  size        int
+  compactness float64
+  iterations  int
+  iterate     string
+  tolerance   float64
+  seeding     string
+  distance    string
+  space       string
+  neighbours  int
+  minSize     int
+  minRatio    float64
+  merge       string
+  stats       string
+  orient      bool
+}
+
+func addSettings(fs *flag.FlagSet) *settings {
+  s := new(settings)
+  fs.IntVar(&s.pixels, "pixels", 0, "number of superpixels (overrides -size)")
+  fs.IntVar(&s.size, "size", 40, "superpixel size in pixels")
+  fs.Float64Var(&s.compactness, "c", 20, "superpixel compactness")
+  fs.IntVar(&s.iterations, "i", 10, "number of iterations, or the maximum with -iterate converge")
+  fs.StringVar(&s.iterate, "iterate", "fixed", "iteration mode: fixed or converge")
+  fs.Float64Var(&s.tolerance, "tolerance", 0.25, "mean center movement in pixels that ends -iterate converge")
+  fs.StringVar(&s.seeding, "seeding", "grid", "seeding: grid, perturbed or hex")
+  fs.StringVar(&s.distance, "distance", "slic", "distance metric: slic or slico")
+  fs.StringVar(&s.space, "space", "lab", "color space: lab, luv, oklab, hsv or ycbcr")
+  fs.IntVar(&s.neighbours, "connectivity", 4, "pixels joined into a superpixel must share an edge (4) or may share a corner (8)")
+  fs.IntVar(&s.minSize, "min-size", 0, "smallest superpixel kept, in pixels; -1 keeps every fragment (0 uses -min-ratio)")
+  fs.Float64Var(&s.minRatio, "min-ratio", 0.25, "merge fragments no larger than this fraction of the expected size")
+  fs.StringVar(&s.merge, "merge", "adjacent", "where small fragments go: adjacent or color (the closest mean color)")
+  fs.BoolVar(&s.orient, "orient", true, "rotate JPEG input upright using its EXIF orientation")
+  fs.StringVar(&s.stats, "stats", "", "also write JSON statistics to this file (- for standard output)")
+  return s
+}
+
+var (
+  seedings  = map[string]slic.Seeding{"grid": slic.SeedGrid, "perturbed": slic.SeedPerturbed, "hex": slic.SeedHex}
+  distances = map[string]slic.Distance{"slic": slic.DistanceSLIC, "slico": slic.DistanceSLICO}
+  merges    = map[string]slic.Merge{"adjacent": slic.MergeAdjacent, "color": slic.MergeClosestColor}
+)
+
+func (s *settings) options() (opts slic.Options, err error) {
+  var ok bool
+  if opts.Seeding, ok = seedings[s.seeding]; !ok {
+    return opts, fmt.Errorf("unknown seeding %q", s.seeding)
+  }
+  if opts.Distance, ok = distances[s.distance]; !ok {
+    return opts, fmt.Errorf("unknown distance %q", s.distance)
+  }
+  if opts.Merge, ok = merges[s.merge]; !ok {
+    return opts, fmt.Errorf("unknown merge policy %q", s.merge)
+  }
+  switch s.neighbours {
+  case 4:
+    opts.Connectivity = slic.Connect4
+  case 8:
+    opts.Connectivity = slic.Connect8
+  default:
+    return opts, fmt.Errorf("-connectivity must be 4 or 8, not %d", s.neighbours)
+  }
+  if s.minRatio <= 0 {
+    return opts, errors.New("-min-ratio must be positive")
+  }
+  opts.MinSize, opts.MinSizeRatio = s.minSize, s.minRatio
+  space, ok := colorspace.ByName(s.space)
+  if !ok {
+    return opts, fmt.Errorf("unknown color space %q", s.space)
+  }
+  if space != colorspace.LabSpace {
+    // Lab is the default and has a faster conversion path.
+    opts.Space = space
+  }
+  switch s.iterate {
+  case "fixed":
+  case "converge":
+    if s.tolerance <= 0 {
+      return opts, errors.New("-tolerance must be positive")
+    }
+    opts.Convergence = s.tolerance
+  default:
+    return opts, fmt.Errorf("unknown iteration mode %q", s.iterate)
+  }
+  return opts, nil
+}
+
+// result is a finished segmentation of one image.
+type result struct {
+  input     string
+  img       image.Image
+  slic      *slic.SLIC
+  requested int
+  elapsed   time.Duration
+}
+
+// segment decodes the image named by the single argument left in fs, or
+// standard input for "-", and segments it.
+func segment(fs *flag.FlagSet, s *settings) (*result, error) {
+  if fs.NArg() != 1 {
+    return nil, errors.New("expected one image argument, or - for standard input")
+  }
+  opts, err := s.options()
+  if err != nil {
+    return nil, err
+  }
+  return segmentFile(fs.Arg(0), s, opts)
+}
+
+func segmentFile(input string, s *settings, opts slic.Options) (*result, error) {
+  f := os.Stdin
+  if input != stdio {
+    var err error
+    if f, err = os.Open(input); err != nil {
+      return nil, err
+    }
+    defer f.Close()
+  }
+  img, err := s.decode(f)
+  if err != nil {
+    return nil, fmt.Errorf("could not decode %s: %v", input, err)
+  }
+  return segmentImage(context.Background(), input, img, s, opts)
+}
+
+// decode decodes an image, applying its EXIF orientation unless -orient is
+// off.
+func (s *settings) decode(r io.Reader) (image.Image, error) {
+  if !s.orient {
+    img, _, err := image.Decode(r)
+    return img, err
+  }
+  img, _, err := exif.Decode(r)
+  return img, err
+}
+
+// segmentImage segments img, which was read from input, stopping early if ctx
+// is done.
+func segmentImage(ctx context.Context, input string, img image.Image, s *settings, opts slic.Options) (*result, error) {
+  size := img.Bounds().Size()
+  supsz, requested := s.size, 0
+  if s.pixels > 0 {
+    supsz, requested = slic.SuperPixelSizeForCount(size.X, size.Y, s.pixels), s.pixels
+  }
+  if supsz <= 0 {
+    return nil, errors.New("superpixel size must be positive")
+  }
+  if requested == 0 {
+    requested = size.X * size.Y / supsz
+  }
+
+  start := time.Now()
+  sl := slic.MakeSlicWithOptions(img, s.compactness, supsz, opts)
+  if err := sl.RunContext(ctx, s.iterations); err != nil {
+    return nil, err
+  }
+  return &result{
+    input:     input,
+    img:       img,
+    slic:      sl,
+    requested: requested,
+    elapsed:   time.Since(start),
+  }, nil
+}
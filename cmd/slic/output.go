@@ -0,0 +1,191 @@
+package main
+
+import (
+  "bufio"
+  "encoding/json"
+  "fmt"
+  "image"
+  "image/color"
+  "image/gif"
+  "image/jpeg"
+  "image/png"
+  "io"
+  "math/rand"
+  "os"
+  "path/filepath"
+  "strconv"
+  "strings"
+
+  "github.com/kurige/SLIC/labelio"
+  "github.com/kurige/SLIC/netpbm"
+)
+
+// stdio is the file name that stands for standard input or output.
+const stdio = "-"
+
+// defaultOutput names an output after the input image, in the current
+// directory: photo.jpg becomes photo_edges.png. Output for an image read from
+// standard input goes to standard output.
+func defaultOutput(input, suffix, ext string) string {
+  if input == stdio {
+    return stdio
+  }
+  base := filepath.Base(input)
+  return strings.TrimSuffix(base, filepath.Ext(base)) + "_" + suffix + "." + ext
+}
+
+// formatOf returns format if set, then the extension of path, and then
+// fallback, which is what standard output is written as by default.
+func formatOf(path, format, fallback string) string {
+  if format != "" {
+    return strings.ToLower(format)
+  }
+  if ext := filepath.Ext(path); ext != "" && path != stdio {
+    return strings.TrimPrefix(strings.ToLower(ext), ".")
+  }
+  return fallback
+}
+
+// writeFile writes path through a temporary file in the same directory, so
+// that an interrupted run never leaves a truncated output behind for a later
+// batch run to mistake for a finished one. An existing output keeps its
+// permissions and a symbolic link keeps pointing at the replaced file. A path
+// of "-" is standard output.
+func writeFile(path string, write func(io.Writer) error) error {
+  if path == stdio {
+    w := bufio.NewWriter(os.Stdout)
+    if err := write(w); err != nil {
+      return err
+    }
+    return w.Flush()
+  }
+  if target, err := filepath.EvalSymlinks(path); err == nil {
+    // Replace what a link points to, not the link.
+    path = target
+  } else if fi, lerr := os.Lstat(path); lerr == nil && fi.Mode()&os.ModeSymlink != 0 {
+    // A dangling link is written through in place.
+    return writeInPlace(path, write)
+  }
+  f, err := createTemp(path)
+  if err != nil {
+    return err
+  }
+  if fi, serr := os.Stat(path); serr == nil {
+    err = f.Chmod(fi.Mode().Perm())
+  }
+  if err == nil {
+    err = write(f)
+  }
+  if cerr := f.Close(); err == nil {
+    err = cerr
+  }
+  if err == nil {
+    err = os.Rename(f.Name(), path)
+  }
+  if err != nil {
+    os.Remove(f.Name())
+  }
+  return err
+}
+
+// createTemp creates a hidden temporary file next to path. Unlike
+// os.CreateTemp it asks for mode 0666, so new outputs get the permissions
+// the umask allows rather than 0600.
+func createTemp(path string) (*os.File, error) {
+  dir, base := filepath.Split(path)
+  for try := 0; ; try++ {
+    name := filepath.Join(dir, "."+base+"."+strconv.FormatUint(uint64(rand.Uint32()), 36))
+    f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+    if os.IsExist(err) && try < 100 {
+      continue
+    }
+    return f, err
+  }
+}
+
+func writeInPlace(path string, write func(io.Writer) error) error {
+  f, err := os.Create(path)
+  if err != nil {
+    return err
+  }
+  err = write(f)
+  if cerr := f.Close(); err == nil {
+    err = cerr
+  }
+  return err
+}
+
+func writeImage(path, format string, img image.Image) error {
+  format = formatOf(path, format, "png")
+  var encode func(io.Writer, image.Image) error
+  switch format {
+  case "png":
+    encode = png.Encode
+  case "jpg", "jpeg":
+    encode = func(w io.Writer, img image.Image) error {
+      return jpeg.Encode(w, img, &jpeg.Options{Quality: 90})
+    }
+  case "gif":
+    encode = func(w io.Writer, img image.Image) error { return gif.Encode(w, img, nil) }
+  case "ppm", "pgm", "pnm":
+    encode = func(w io.Writer, img image.Image) error { return netpbm.Encode(w, img, nil) }
+  case "pam":
+    encode = func(w io.Writer, img image.Image) error {
+      return netpbm.Encode(w, img, &netpbm.Options{Format: netpbm.PAM})
+    }
+  default:
+    return fmt.Errorf("unknown image format %q", format)
+  }
+  return writeFile(path, func(w io.Writer) error { return encode(w, img) })
+}
+
+func writeLabels(path, format string, r *result) error {
+  format = formatOf(path, format, "png")
+  if !labelio.IsFormat(format) {
+    return fmt.Errorf("unknown label format %q", format)
+  }
+  size := r.img.Bounds().Size()
+  return writeFile(path, func(w io.Writer) error {
+    return labelio.Write(w, format, size.X, size.Y, r.slic.Labels)
+  })
+}
+
+// writeStats writes a summary as text or JSON. An empty path is standard
+// output.
+func writeStats(path, format string, st summary) error {
+  if path == "" {
+    path = stdio
+  }
+  var write func(io.Writer) error
+  switch formatOf(path, format, "text") {
+  case "json":
+    write = func(w io.Writer) error { return writeJSON(w, st) }
+  case "text", "txt":
+    write = st.writeText
+  default:
+    return fmt.Errorf("unknown stats format %q", format)
+  }
+  return writeFile(path, write)
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+  enc := json.NewEncoder(w)
+  enc.SetIndent("", "  ")
+  return enc.Encode(v)
+}
+
+// parseColor reads a color as #rgb, #rrggbb or #rrggbbaa.
+func parseColor(s string) (color.Color, error) {
+  hex := strings.TrimPrefix(s, "#")
+  if len(hex) == 3 {
+    hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+  }
+  if len(hex) == 6 {
+    hex += "ff"
+  }
+  v, err := strconv.ParseUint(hex, 16, 32)
+  if len(hex) != 8 || err != nil {
+    return nil, fmt.Errorf("bad color %q", s)
+  }
+  return color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+  "bytes"
+  "context"
+  "encoding/json"
+  "errors"
+  "flag"
+  "fmt"
+  "image"
+  "image/png"
+  "io"
+  "log"
+  "mime"
+  "net/http"
+  "os"
+  "runtime"
+  "time"
+
+  "github.com/kurige/SLIC/labelio"
+)
+
+// server segments uploaded images. See runServe for the API.
+type server struct {
+  maxBytes  int64
+  maxPixels int
+  timeout   time.Duration
+  slots     chan struct{}
+}
+
+func runServe(args []string) error {
+  fs := newFlagSet("serve", "")
+  addr := fs.String("addr", ":8080", "address to listen on")
+  maxBytes := fs.Int64("max-bytes", 32<<20, "largest accepted upload in bytes")
+  maxPixels := fs.Int("max-pixels", 50000000, "largest accepted image in pixels")
+  concurrency := fs.Int("concurrency", runtime.NumCPU(), "number of images read and segmented at once")
+  timeout := fs.Duration("timeout", 60*time.Second, "time limit per request, including waiting for a free slot and reading the upload")
+  fs.Parse(args)
+  if *concurrency < 1 {
+    *concurrency = 1
+  }
+
+  srv := &server{
+    maxBytes:  *maxBytes,
+    maxPixels: *maxPixels,
+    timeout:   *timeout,
+    slots:     make(chan struct{}, *concurrency),
+  }
+  mux := http.NewServeMux()
+  mux.HandleFunc("/segment", srv.segment)
+  hs := &http.Server{
+    Addr:              *addr,
+    Handler:           mux,
+    ReadHeaderTimeout: 10 * time.Second,
+    // Backstops for the per-request deadline, which the handler also
+    // applies to reading the upload.
+    ReadTimeout:  10*time.Second + *timeout,
+    WriteTimeout: 30*time.Second + *timeout,
+  }
+  log.Println("slic: listening on", *addr)
+  return hs.ListenAndServe()
+}
+
+// httpError is an error with the status code to report it with.
+type httpError struct {
+  code int
+  msg  string
+}
+
+func (e *httpError) Error() string { return e.msg }
+
+func badRequest(format string, a ...interface{}) error {
+  return &httpError{http.StatusBadRequest, fmt.Sprintf(format, a...)}
+}
+
+// segment handles POST /segment. The image is the "image" field of a
+// multipart form, or else the whole request body. Query parameters are the
+// segmentation flags of the other commands (pixels, size, c, i, iterate,
+// tolerance, seeding, distance, space, connectivity, min-size, min-ratio,
+// merge, orient) plus:
+//
+//	output  labels (default), edges, json or geojson
+//	format  label map format for output=labels: png, pgm, npy, raw or seg
+func (srv *server) segment(w http.ResponseWriter, r *http.Request) {
+  if r.Method != http.MethodPost {
+    w.Header().Set("Allow", http.MethodPost)
+    http.Error(w, "use POST", http.StatusMethodNotAllowed)
+    return
+  }
+  ctx, cancel := context.WithTimeout(r.Context(), srv.timeout)
+  defer cancel()
+
+  if err := srv.handle(ctx, w, r); err != nil {
+    var he *httpError
+    switch {
+    case errors.As(err, &he):
+      http.Error(w, he.msg, he.code)
+    case errors.Is(err, context.DeadlineExceeded):
+      http.Error(w, "segmentation timed out", http.StatusServiceUnavailable)
+    case errors.Is(err, context.Canceled):
+      // The client went away; there is nobody to answer.
+    default:
+      log.Println("slic:", err)
+      http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+  }
+}
+
+func (srv *server) handle(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+  s, query, err := parseQuery(r)
+  if err != nil {
+    return err
+  }
+  opts, err := s.options()
+  if err != nil {
+    return badRequest("%v", err)
+  }
+  output, format := query["output"], query["format"]
+  switch output {
+  case "":
+    output = "labels"
+  case "labels", "edges", "json", "geojson":
+  default:
+    return badRequest("unknown output %q", output)
+  }
+  if format == "" {
+    format = "png"
+  }
+  if output == "labels" && !labelio.IsFormat(format) {
+    return badRequest("unknown label format %q", format)
+  }
+
+  // Take a slot before reading the upload, so that the concurrency limit
+  // also bounds the memory held by uploads and decoded images.
+  select {
+  case srv.slots <- struct{}{}:
+    defer func() { <-srv.slots }()
+  case <-ctx.Done():
+    return ctx.Err()
+  }
+  if deadline, ok := ctx.Deadline(); ok {
+    // Reading the body does not watch ctx. Not every ResponseWriter supports
+    // deadlines, in which case the server's ReadTimeout still applies.
+    http.NewResponseController(w).SetReadDeadline(deadline)
+  }
+  img, err := srv.readImage(w, r, s)
+  if err != nil {
+    return err
+  }
+
+  res, err := segmentImage(ctx, "upload", img, s, opts)
+  if err != nil {
+    return err
+  }
+
+  var buf bytes.Buffer
+  switch output {
+  case "labels":
+    size := img.Bounds().Size()
+    err = labelio.Write(&buf, format, size.X, size.Y, res.slic.Labels)
+    w.Header().Set("Content-Type", labelTypes[format])
+  case "edges":
+    err = png.Encode(&buf, res.slic.DrawEdgesToImage(img))
+    w.Header().Set("Content-Type", "image/png")
+  case "json":
+    err = json.NewEncoder(&buf).Encode(polygonsDocument(res))
+    w.Header().Set("Content-Type", "application/json")
+  case "geojson":
+    err = json.NewEncoder(&buf).Encode(geoJSONDocument(res))
+    w.Header().Set("Content-Type", "application/geo+json")
+  }
+  if err != nil {
+    return err
+  }
+  w.Header().Set("X-Superpixels", fmt.Sprint(res.slic.LabelCount()))
+  _, err = buf.WriteTo(w)
+  return err
+}
+
+var labelTypes = map[string]string{
+  "png": "image/png",
+  "pgm": "image/x-portable-graymap",
+  "npy": "application/octet-stream",
+  "raw": "application/octet-stream",
+  "seg": "text/plain",
+}
+
+// parseQuery applies the segmentation query parameters to a fresh set of
+// settings, returning the other parameters.
+func parseQuery(r *http.Request) (*settings, map[string]string, error) {
+  fs := flag.NewFlagSet("query", flag.ContinueOnError)
+  fs.SetOutput(io.Discard)
+  s := addSettings(fs)
+  rest := make(map[string]string)
+  for key, values := range r.URL.Query() {
+    value := values[len(values)-1]
+    switch key {
+    case "output", "format":
+      rest[key] = value
+    case "stats":
+      return nil, nil, badRequest("unknown parameter %q", key)
+    default:
+      if fs.Lookup(key) == nil {
+        return nil, nil, badRequest("unknown parameter %q", key)
+      }
+      if err := fs.Set(key, value); err != nil {
+        return nil, nil, badRequest("bad %s: %v", key, err)
+      }
+    }
+  }
+  return s, rest, nil
+}
+
+// readImage decodes the upload, checking its dimensions before decoding the
+// pixels.
+func (srv *server) readImage(w http.ResponseWriter, r *http.Request, s *settings) (image.Image, error) {
+  body := http.MaxBytesReader(w, r.Body, srv.maxBytes)
+  var src io.Reader = body
+  if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "multipart/form-data" {
+    r.Body = body
+    if err := r.ParseMultipartForm(srv.maxBytes); err != nil {
+      return nil, uploadError(err)
+    }
+    f, _, err := r.FormFile("image")
+    if err != nil {
+      return nil, badRequest("missing image field: %v", err)
+    }
+    defer f.Close()
+    src = f
+  }
+  data, err := io.ReadAll(src)
+  if err != nil {
+    return nil, uploadError(err)
+  }
+
+  config, _, err := image.DecodeConfig(bytes.NewReader(data))
+  if err != nil {
+    return nil, badRequest("could not decode image: %v", err)
+  }
+  if config.Width*config.Height > srv.maxPixels {
+    return nil, &httpError{http.StatusRequestEntityTooLarge,
+      fmt.Sprintf("image has %d pixels, the limit is %d", config.Width*config.Height, srv.maxPixels)}
+  }
+  img, err := s.decode(bytes.NewReader(data))
+  if err != nil {
+    return nil, badRequest("could not decode image: %v", err)
+  }
+  return img, nil
+}
+
+func uploadError(err error) error {
+  if errors.Is(err, os.ErrDeadlineExceeded) {
+    return context.DeadlineExceeded
+  }
+  var mbe *http.MaxBytesError
+  if errors.As(err, &mbe) {
+    return &httpError{http.StatusRequestEntityTooLarge, fmt.Sprintf("upload is larger than %d bytes", mbe.Limit)}
+  }
+  return badRequest("could not read upload: %v", err)
+}
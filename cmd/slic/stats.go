@@ -0,0 +1,120 @@
+package main
+
+import (
+  "fmt"
+  "io"
+
+  "github.com/kurige/SLIC/eval"
+)
+
+// summary is the statistics output of every command.
+type summary struct {
+  Image  string `json:"image"`
+  Width  int    `json:"width"`
+  Height int    `json:"height"`
+  Space  string `json:"space"`
+  // Requested is the superpixel count asked for, Seeded the number of
+  // centers MakeSlic placed after fitting a grid to the image, and
+  // Superpixels the number left after connectivity enforcement.
+  Requested          int          `json:"requested"`
+  Seeded             int          `json:"seeded"`
+  Superpixels        int          `json:"superpixels"`
+  Iterations         int          `json:"iterations"`
+  MeanSize           float64      `json:"mean_size"`
+  Compactness        float64      `json:"compactness"`
+  ExplainedVariation float64      `json:"explained_variation"`
+  Seconds            float64      `json:"seconds"`
+  Timings            phaseTimings `json:"timings"`
+  Labels             []labelStats `json:"labels"`
+}
+
+// phaseTimings are slic.Timings in seconds.
+type phaseTimings struct {
+  Conversion   float64 `json:"conversion"`
+  Assignment   float64 `json:"assignment"`
+  Update       float64 `json:"update"`
+  Connectivity float64 `json:"connectivity"`
+}
+
+type labelStats struct {
+  Label int        `json:"label"`
+  Seed  int        `json:"seed"`
+  Area  int        `json:"area"`
+  X     float64    `json:"x"`
+  Y     float64    `json:"y"`
+  Color [3]float64 `json:"color"`
+  RGB   string     `json:"rgb"`
+}
+
+func (r *result) summarize() summary {
+  var (
+    s    = r.slic
+    size = r.img.Bounds().Size()
+    n    = s.LabelCount()
+  )
+  st := summary{
+    Image:              r.input,
+    Width:              size.X,
+    Height:             size.Y,
+    Space:              s.Space().Name(),
+    Requested:          r.requested,
+    Seeded:             len(s.Seeds()),
+    Superpixels:        n,
+    Iterations:         s.Iterations,
+    MeanSize:           float64(size.X*size.Y) / float64(n),
+    Compactness:        eval.Compactness(s.Labels, size.X, size.Y),
+    ExplainedVariation: eval.ExplainedVariation(s.Labels, r.img),
+    Seconds:            r.elapsed.Seconds(),
+    Timings: phaseTimings{
+      Conversion:   s.Timings.Conversion.Seconds(),
+      Assignment:   s.Timings.Assignment.Seconds(),
+      Update:       s.Timings.Update.Seconds(),
+      Connectivity: s.Timings.Connectivity.Seconds(),
+    },
+    Labels: make([]labelStats, n),
+  }
+
+  for label, sp := range s.Superpixels {
+    R, G, B := s.Space().ToRGB(sp.L, sp.A, sp.B)
+    st.Labels[label] = labelStats{
+      Label: label,
+      Seed:  sp.Seed,
+      Area:  sp.Count,
+      X:     sp.X,
+      Y:     sp.Y,
+      Color: [3]float64{sp.L, sp.A, sp.B},
+      RGB:   fmt.Sprintf("#%02x%02x%02x", R, G, B),
+    }
+  }
+  return st
+}
+
+// writeStats writes the JSON statistics if -stats was given.
+func (r *result) writeStats(s *settings) error {
+  if s.stats == "" {
+    return nil
+  }
+  return writeStats(s.stats, "json", r.summarize())
+}
+
+// writeText writes the summary without the per-label table.
+func (st summary) writeText(w io.Writer) error {
+  _, err := fmt.Fprintf(w, `image:               %s
+size:                %dx%d
+color space:         %s
+superpixels:         %d (requested %d, seeded %d)
+mean size:           %.1f px
+iterations:          %d
+compactness:         %.4f
+explained variation: %.4f
+time:                %.3fs
+  conversion:        %.3fs
+  assignment:        %.3fs
+  update:            %.3fs
+  connectivity:      %.3fs
+`, st.Image, st.Width, st.Height, st.Space, st.Superpixels, st.Requested, st.Seeded,
+    st.MeanSize, st.Iterations, st.Compactness, st.ExplainedVariation, st.Seconds,
+    st.Timings.Conversion, st.Timings.Assignment, st.Timings.Update, st.Timings.Connectivity)
+  return err
+}
+
@@ -0,0 +1,145 @@
+// Package colorspace converts images to color spaces other than CIELAB, for
+// comparing how the choice of space affects segmentation. Every space has
+// plain conversion functions mirroring lab.Rgb2lab and lab.Lab2rgb, a
+// color.Color type and model, and a Space that SLIC can cluster in.
+//
+// Like package lab, every space converts translucent colors as if they were
+// composited over black.
+package colorspace
+
+import (
+  "image"
+  "image/color"
+  "strings"
+)
+
+// Space is a color space SLIC can cluster in. Its coordinates must be
+// meaningful under Euclidean distance and are scaled so that the range of
+// colors spans roughly 100 units per axis, as in CIELAB, so compactness
+// values carry over between spaces. They are therefore not always the
+// conventional coordinates of the space; see the individual spaces.
+type Space interface {
+  Name() string
+  FromColor(c color.Color) (x, y, z float64)
+  ToRGB(x, y, z float64) (R, G, B uint8)
+}
+
+// Spaces lists every Space in this package.
+var Spaces = []Space{LabSpace, LuvSpace, OKLabSpace, HSVSpace, YCbCrSpace}
+
+// ByName returns the space with the given Name, ignoring case.
+func ByName(name string) (Space, bool) {
+  for _, s := range Spaces {
+    if strings.EqualFold(s.Name(), name) {
+      return s, true
+    }
+  }
+  return nil, false
+}
+
+// Color is a color in Space coordinates.
+type Color struct {
+  Space   Space
+  X, Y, Z float64
+}
+
+func (c Color) RGBA() (uint32, uint32, uint32, uint32) {
+  R, G, B := c.Space.ToRGB(c.X, c.Y, c.Z)
+  return rgba(R, G, B)
+}
+
+// Model returns the color.Model converting to Color in space s.
+func Model(s Space) color.Model {
+  return color.ModelFunc(func(c color.Color) color.Color {
+    if c, ok := c.(Color); ok && c.Space == s {
+      return c
+    }
+    x, y, z := s.FromColor(c)
+    return Color{s, x, y, z}
+  })
+}
+
+// Image is an image in Space coordinates, laid out like lab.Image.
+type Image struct {
+  Space  Space
+  Pix    []float64
+  Stride int
+  Rect   image.Rectangle
+}
+
+func NewImage(r image.Rectangle, s Space) *Image {
+  w, h := r.Dx(), r.Dy()
+  buf := make([]float64, 3*w*h)
+  return &Image{s, buf, 3 * w, r}
+}
+
+func (p *Image) ColorModel() color.Model { return Model(p.Space) }
+
+func (p *Image) Bounds() image.Rectangle { return p.Rect }
+
+func (p *Image) At(x, y int) color.Color {
+  X, Y, Z := p.ChannelsAt(x, y)
+  return Color{p.Space, X, Y, Z}
+}
+
+// ChannelsAt returns the Space coordinates of the pixel at (x, y).
+func (p *Image) ChannelsAt(x, y int) (X, Y, Z float64) {
+  if !(image.Point{x, y}.In(p.Rect)) {
+    return
+  }
+  i := p.PixOffset(x, y)
+  return p.Pix[i+0], p.Pix[i+1], p.Pix[i+2]
+}
+
+func (p *Image) PixOffset(x, y int) int {
+  return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*3
+}
+
+func (p *Image) Set(x, y int, c color.Color) {
+  if !(image.Point{x, y}.In(p.Rect)) {
+    return
+  }
+  i := p.PixOffset(x, y)
+  c1 := p.ColorModel().Convert(c).(Color)
+  p.Pix[i+0] = c1.X
+  p.Pix[i+1] = c1.Y
+  p.Pix[i+2] = c1.Z
+}
+
+func (p *Image) SubImage(r image.Rectangle) image.Image {
+  r = r.Intersect(p.Rect)
+  if r.Empty() {
+    return &Image{Space: p.Space}
+  }
+  i := p.PixOffset(r.Min.X, r.Min.Y)
+  return &Image{
+    Space:  p.Space,
+    Pix:    p.Pix[i:],
+    Stride: p.Stride,
+    Rect:   r,
+  }
+}
+
+// ImageToSpace converts img to space s, with the result's bounds starting at
+// (0, 0) as for lab.ImageToLab.
+func ImageToSpace(img image.Image, s Space) *Image {
+  b := img.Bounds()
+  canvas := NewImage(image.Rect(0, 0, b.Dx(), b.Dy()), s)
+  for y := b.Min.Y; y < b.Max.Y; y++ {
+    for x := b.Min.X; x < b.Max.X; x++ {
+      i := canvas.PixOffset(x-b.Min.X, y-b.Min.Y)
+      canvas.Pix[i+0], canvas.Pix[i+1], canvas.Pix[i+2] = s.FromColor(img.At(x, y))
+    }
+  }
+  return canvas
+}
+
+func rgba(R, G, B uint8) (uint32, uint32, uint32, uint32) {
+  r := uint32(R)
+  r |= r << 8
+  g := uint32(G)
+  g |= g << 8
+  b := uint32(B)
+  b |= b << 8
+  return r, g, b, 0xffff
+}
@@ -0,0 +1,157 @@
+package colorspace
+
+import (
+  "image"
+  "image/color"
+  "math"
+  "testing"
+
+  . "github.com/franela/goblin"
+  "github.com/kurige/SLIC/lab"
+)
+
+func near(a, b, tolerance float64) bool {
+  return math.Abs(a-b) <= tolerance
+}
+
+func abs8(a, b uint8) int {
+  if a > b {
+    return int(a - b)
+  }
+  return int(b - a)
+}
+
+// samples covers the corners of the RGB cube and some in-between colors.
+var samples = [][3]uint8{
+  {0, 0, 0}, {255, 255, 255}, {255, 0, 0}, {0, 255, 0}, {0, 0, 255},
+  {255, 255, 0}, {0, 255, 255}, {255, 0, 255}, {127, 15, 15},
+  {15, 127, 15}, {15, 15, 127}, {128, 128, 128}, {200, 150, 100},
+}
+
+func TestConversions(t *testing.T) {
+  _g := Goblin(t)
+  _g.Describe("CIELUV", func() {
+    _g.It("Maps white and red to reference values", func() {
+      l, u, v := Rgb2luv(255, 255, 255)
+      _g.Assert(near(l, 100, 0.01) && near(u, 0, 0.05) && near(v, 0, 0.05)).IsTrue()
+      l, u, v = Rgb2luv(255, 0, 0)
+      _g.Assert(near(l, 53.24, 0.05) && near(u, 175.01, 0.1) && near(v, 37.76, 0.1)).IsTrue()
+    })
+  })
+  _g.Describe("Oklab", func() {
+    _g.It("Maps white and red to reference values", func() {
+      l, a, b := Rgb2oklab(255, 255, 255)
+      _g.Assert(near(l, 1, 1e-4) && near(a, 0, 1e-4) && near(b, 0, 1e-4)).IsTrue()
+      l, a, b = Rgb2oklab(255, 0, 0)
+      _g.Assert(near(l, 0.62796, 1e-4) && near(a, 0.22486, 1e-4) && near(b, 0.12585, 1e-4)).IsTrue()
+    })
+  })
+  _g.Describe("HSV", func() {
+    _g.It("Maps primaries and grays", func() {
+      h, s, v := Rgb2hsv(0, 0, 255)
+      _g.Assert([]float64{h, s, v}).Equal([]float64{240, 1, 1})
+      h, s, v = Rgb2hsv(255, 0, 255)
+      _g.Assert([]float64{h, s, v}).Equal([]float64{300, 1, 1})
+      h, s, v = Rgb2hsv(51, 51, 51)
+      _g.Assert([]float64{h, s, v}).Equal([]float64{0, 0, 0.2})
+    })
+  })
+  _g.Describe("YCbCr", func() {
+    _g.It("Agrees with image/color", func() {
+      for _, c := range samples {
+        y, cb, cr := Rgb2ycbcr(c[0], c[1], c[2])
+        Y, Cb, Cr := color.RGBToYCbCr(c[0], c[1], c[2])
+        _g.Assert(near(y, float64(Y), 1) && near(cb, float64(Cb), 1) && near(cr, float64(Cr), 1)).IsTrue()
+      }
+    })
+  })
+  _g.Describe("Round trips", func() {
+    _g.It("Convert back to the same RGB", func() {
+      for _, c := range samples {
+        for _, got := range [][3]uint8{
+          rt(Luv2rgb(Rgb2luv(c[0], c[1], c[2]))),
+          rt(Oklab2rgb(Rgb2oklab(c[0], c[1], c[2]))),
+          rt(Hsv2rgb(Rgb2hsv(c[0], c[1], c[2]))),
+          rt(Ycbcr2rgb(Rgb2ycbcr(c[0], c[1], c[2]))),
+        } {
+          _g.Assert(got).Equal(c)
+        }
+      }
+    })
+    _g.It("Hold for every Space", func() {
+      for _, s := range Spaces {
+        for _, c := range samples {
+          x, y, z := s.FromColor(color.RGBA{c[0], c[1], c[2], 255})
+          R, G, B := s.ToRGB(x, y, z)
+          _g.Assert(abs8(R, c[0]) <= 1 && abs8(G, c[1]) <= 1 && abs8(B, c[2]) <= 1).IsTrue()
+        }
+      }
+    })
+    _g.It("Hold for the color types", func() {
+      for _, m := range []color.Model{LuvModel, OKLabModel, HSVModel, YCbCrModel} {
+        for _, c := range samples {
+          in := color.RGBA{c[0], c[1], c[2], 255}
+          _g.Assert(color.RGBAModel.Convert(m.Convert(in))).Equal(in)
+        }
+      }
+    })
+  })
+  _g.Describe("Spaces", func() {
+    _g.It("Span about 100 units like Lab", func() {
+      for _, s := range Spaces {
+        x0, _, _ := s.FromColor(color.Black)
+        x1, _, _ := s.FromColor(color.White)
+        _g.Assert(near(x1-x0, 100, 0.5)).IsTrue()
+      }
+    })
+    _g.It("Are found by name", func() {
+      s, ok := ByName("OKLab")
+      _g.Assert(ok).IsTrue()
+      _g.Assert(s == OKLabSpace).IsTrue()
+      _, ok = ByName("cmyk")
+      _g.Assert(ok).IsFalse()
+    })
+    _g.It("Has no hue seam in HSV", func() {
+      _, y0, z0 := HSVSpace.FromColor(color.RGBA{255, 0, 2, 255})
+      _, y1, z1 := HSVSpace.FromColor(color.RGBA{255, 2, 0, 255})
+      _g.Assert(math.Hypot(y1-y0, z1-z0) < 2).IsTrue()
+    })
+    _g.It("Treat translucent pixels like lab.Image", func() {
+      src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+      src.Set(0, 0, color.NRGBA{200, 150, 100, 128})
+      src.Set(1, 0, color.NRGBA{200, 150, 100, 0})
+      s, _ := ByName("lab")
+      img := ImageToSpace(src, s)
+      want := lab.ImageToLab(src)
+      for x := 0; x < 2; x++ {
+        l, a, b := img.ChannelsAt(x, 0)
+        c := want.LabAt(x, 0)
+        _g.Assert(near(l, c.L, 1e-9) && near(a, c.A, 1e-9) && near(b, c.B, 1e-9)).IsTrue()
+      }
+      // Composited over black, half transparent orange is darker than opaque.
+      l, _, _ := s.FromColor(color.RGBA{200, 150, 100, 255})
+      _g.Assert(want.LabAt(0, 0).L < l).IsTrue()
+      for _, sp := range Spaces {
+        x0, y0, z0 := sp.FromColor(color.Transparent)
+        x1, y1, z1 := sp.FromColor(color.Black)
+        _g.Assert([]float64{x0, y0, z0}).Equal([]float64{x1, y1, z1})
+      }
+    })
+  })
+  _g.Describe("Image", func() {
+    _g.It("Converts and crops like lab.Image", func() {
+      src := image.NewRGBA(image.Rect(5, 5, 9, 8))
+      src.Set(6, 6, color.RGBA{200, 150, 100, 255})
+      img := ImageToSpace(src, OKLabSpace)
+      _g.Assert(img.Bounds()).Equal(image.Rect(0, 0, 4, 3))
+      _g.Assert(color.RGBAModel.Convert(img.At(1, 1))).Equal(color.RGBA{200, 150, 100, 255})
+      sub := img.SubImage(image.Rect(1, 1, 3, 3))
+      _g.Assert(sub.At(1, 1)).Equal(img.At(1, 1))
+      img.Set(0, 0, color.White)
+      x, _, _ := img.ChannelsAt(0, 0)
+      _g.Assert(near(x, 100, 1e-3)).IsTrue()
+    })
+  })
+}
+
+func rt(R, G, B uint8) [3]uint8 { return [3]uint8{R, G, B} }
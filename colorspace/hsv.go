@@ -0,0 +1,112 @@
+package colorspace
+
+import (
+  "image/color"
+  "math"
+)
+
+// HSV is a color with hue H in degrees [0, 360) and saturation S and value V
+// in [0, 1].
+type HSV struct {
+  H, S, V float64
+}
+
+func (c HSV) RGBA() (uint32, uint32, uint32, uint32) {
+  return rgba(Hsv2rgb(c.H, c.S, c.V))
+}
+
+var HSVModel color.Model = color.ModelFunc(hsvModel)
+
+func hsvModel(c color.Color) color.Color {
+  if _, ok := c.(HSV); ok {
+    return c
+  }
+  h, s, v := rgb2hsv(rgb(c))
+  return HSV{h, s, v}
+}
+
+func Rgb2hsv(R, G, B uint8) (h, s, v float64) {
+  return rgb2hsv(float64(R)/255, float64(G)/255, float64(B)/255)
+}
+
+func Hsv2rgb(h, s, v float64) (R, G, B uint8) {
+  r, g, b := hsv2rgb(h, s, v)
+  return quantize(r), quantize(g), quantize(b)
+}
+
+// HSVSpace is the HSV cone in Cartesian coordinates: 100·V, then the chroma
+// 100·S·V along the hue direction. Unlike raw H, S, V this has no seam at
+// red and no spread of hues among the grays.
+var HSVSpace Space = hsvSpace{}
+
+type hsvSpace struct{}
+
+func (hsvSpace) Name() string { return "hsv" }
+
+func (hsvSpace) FromColor(c color.Color) (x, y, z float64) {
+  h, s, v := rgb2hsv(rgb(c))
+  sin, cos := math.Sincos(h * math.Pi / 180)
+  return v * 100, s * v * cos * 100, s * v * sin * 100
+}
+
+func (hsvSpace) ToRGB(x, y, z float64) (R, G, B uint8) {
+  v := x / 100
+  var h, s float64
+  if v > 0 {
+    s = math.Hypot(y, z) / 100 / v
+    h = math.Atan2(z, y) * 180 / math.Pi
+  }
+  return Hsv2rgb(h, s, v)
+}
+
+func rgb2hsv(r, g, b float64) (h, s, v float64) {
+  max := math.Max(r, math.Max(g, b))
+  min := math.Min(r, math.Min(g, b))
+  v = max
+  d := max - min
+  if max == 0 || d == 0 {
+    return 0, 0, v
+  }
+  s = d / max
+  switch max {
+  case r:
+    h = (g - b) / d
+  case g:
+    h = 2 + (b-r)/d
+  default:
+    h = 4 + (r-g)/d
+  }
+  h *= 60
+  if h < 0 {
+    h += 360
+  }
+  return
+}
+
+func hsv2rgb(h, s, v float64) (r, g, b float64) {
+  s = math.Max(0, math.Min(1, s))
+  h = math.Mod(h, 360)
+  if h < 0 {
+    h += 360
+  }
+  h /= 60
+  i := math.Floor(h)
+  f := h - i
+  p := v * (1 - s)
+  q := v * (1 - s*f)
+  t := v * (1 - s*(1-f))
+  switch int(i) {
+  case 0:
+    return v, t, p
+  case 1:
+    return q, v, p
+  case 2:
+    return p, v, t
+  case 3:
+    return p, q, v
+  case 4:
+    return t, p, v
+  default:
+    return v, p, q
+  }
+}
@@ -0,0 +1,32 @@
+package colorspace
+
+import (
+  "image/color"
+
+  "github.com/kurige/SLIC/lab"
+)
+
+// LabSpace is CIELAB under D65 with the usual L*, a*, b* coordinates, the
+// space SLIC uses by default.
+var LabSpace Space = NewLabSpace(lab.NewConverter(lab.D65.WhitePoint(lab.Observer2)))
+
+// NewLabSpace returns CIELAB as seen by c, for input in another RGB profile or
+// relative to another white point.
+func NewLabSpace(c *lab.Converter) Space {
+  return labSpace{c}
+}
+
+type labSpace struct {
+  c *lab.Converter
+}
+
+func (labSpace) Name() string { return "lab" }
+
+func (s labSpace) FromColor(c color.Color) (x, y, z float64) {
+  R, G, B, _ := c.RGBA()
+  return s.c.Rgb2lab16(uint16(R), uint16(G), uint16(B))
+}
+
+func (s labSpace) ToRGB(x, y, z float64) (R, G, B uint8) {
+  return s.c.Lab2rgbClamped(x, y, z)
+}
@@ -0,0 +1,105 @@
+package colorspace
+
+import (
+  "image/color"
+  "math"
+
+  "github.com/kurige/SLIC/lab"
+)
+
+// Luv is a CIELUV color under D65, with L in [0, 100].
+type Luv struct {
+  L, U, V float64
+}
+
+func (c Luv) RGBA() (uint32, uint32, uint32, uint32) {
+  return rgba(Luv2rgb(c.L, c.U, c.V))
+}
+
+var LuvModel color.Model = color.ModelFunc(luvModel)
+
+func luvModel(c color.Color) color.Color {
+  if _, ok := c.(Luv); ok {
+    return c
+  }
+  l, u, v := xyz2luv(linear2xyz(linearize(c)))
+  return Luv{l, u, v}
+}
+
+func Rgb2luv(R, G, B uint8) (l, u, v float64) {
+  return xyz2luv(linear2xyz(linearize(rgb8(R, G, B))))
+}
+
+// Luv2rgb converts to sRGB, clamping colors outside the gamut.
+func Luv2rgb(l, u, v float64) (R, G, B uint8) {
+  return fromLinear(xyz2linear(luv2xyz(l, u, v)))
+}
+
+// LuvSpace is CIELUV with the conventional L*, u*, v* coordinates.
+var LuvSpace Space = luvSpace{}
+
+type luvSpace struct{}
+
+func (luvSpace) Name() string { return "luv" }
+
+func (luvSpace) FromColor(c color.Color) (x, y, z float64) {
+  return xyz2luv(linear2xyz(linearize(c)))
+}
+
+func (luvSpace) ToRGB(x, y, z float64) (R, G, B uint8) {
+  return Luv2rgb(x, y, z)
+}
+
+// Reference chromaticity of the D65 white.
+var (
+  refU = 4 * lab.RefX / (lab.RefX + 15*lab.RefY + 3*lab.RefZ)
+  refV = 9 * lab.RefY / (lab.RefX + 15*lab.RefY + 3*lab.RefZ)
+)
+
+// linear2xyz converts linear sRGB to XYZ scaled so that Y is in [0, 100].
+func linear2xyz(r, g, b float64) (x, y, z float64) {
+  x = (r*0.4124 + g*0.3576 + b*0.1805) * 100
+  y = (r*0.2126 + g*0.7152 + b*0.0722) * 100
+  z = (r*0.0193 + g*0.1192 + b*0.9505) * 100
+  return
+}
+
+func xyz2linear(x, y, z float64) (r, g, b float64) {
+  x, y, z = x/100, y/100, z/100
+  r = x*3.2406 + y*-1.5372 + z*-0.4986
+  g = x*-0.9689 + y*1.8758 + z*0.0415
+  b = x*0.0557 + y*-0.2040 + z*1.0570
+  return
+}
+
+func xyz2luv(x, y, z float64) (l, u, v float64) {
+  yr := y / lab.RefY
+  if yr > 216.0/24389 {
+    l = 116*math.Cbrt(yr) - 16
+  } else {
+    l = 24389.0 / 27 * yr
+  }
+  d := x + 15*y + 3*z
+  if d == 0 {
+    return l, 0, 0
+  }
+  u = 13 * l * (4*x/d - refU)
+  v = 13 * l * (9*y/d - refV)
+  return
+}
+
+func luv2xyz(l, u, v float64) (x, y, z float64) {
+  if l <= 0 {
+    return 0, 0, 0
+  }
+  if l > 8 {
+    y = lab.RefY * math.Pow((l+16)/116, 3)
+  } else {
+    y = lab.RefY * l * 27 / 24389
+  }
+  up := u/(13*l) + refU
+  vp := v/(13*l) + refV
+  x = y * 9 * up / (4 * vp)
+  z = y * (12 - 3*up - 20*vp) / (4 * vp)
+  return
+}
@@ -0,0 +1,74 @@
+package colorspace
+
+import (
+  "image/color"
+  "math"
+)
+
+// OKLab is a color in Björn Ottosson's Oklab space, with L in [0, 1] and a,
+// b roughly in [-0.4, 0.4].
+type OKLab struct {
+  L, A, B float64
+}
+
+func (c OKLab) RGBA() (uint32, uint32, uint32, uint32) {
+  return rgba(Oklab2rgb(c.L, c.A, c.B))
+}
+
+var OKLabModel color.Model = color.ModelFunc(oklabModel)
+
+func oklabModel(c color.Color) color.Color {
+  if _, ok := c.(OKLab); ok {
+    return c
+  }
+  l, a, b := linear2oklab(linearize(c))
+  return OKLab{l, a, b}
+}
+
+func Rgb2oklab(R, G, B uint8) (l, a, b float64) {
+  return linear2oklab(linearize(rgb8(R, G, B)))
+}
+
+// Oklab2rgb converts to sRGB, clamping colors outside the gamut.
+func Oklab2rgb(l, a, b float64) (R, G, B uint8) {
+  return fromLinear(oklab2linear(l, a, b))
+}
+
+// OKLabSpace is Oklab with every coordinate multiplied by 100.
+var OKLabSpace Space = oklabSpace{}
+
+type oklabSpace struct{}
+
+func (oklabSpace) Name() string { return "oklab" }
+
+func (oklabSpace) FromColor(c color.Color) (x, y, z float64) {
+  l, a, b := linear2oklab(linearize(c))
+  return l * 100, a * 100, b * 100
+}
+
+func (oklabSpace) ToRGB(x, y, z float64) (R, G, B uint8) {
+  return Oklab2rgb(x/100, y/100, z/100)
+}
+
+func linear2oklab(r, g, b float64) (L, A, B float64) {
+  l := math.Cbrt(0.4122214708*r + 0.5363325363*g + 0.0514459929*b)
+  m := math.Cbrt(0.2119034982*r + 0.6806995451*g + 0.1073969566*b)
+  s := math.Cbrt(0.0883024619*r + 0.2817188376*g + 0.6299787005*b)
+
+  L = 0.2104542553*l + 0.7936177850*m - 0.0040720468*s
+  A = 1.9779984951*l - 2.4285922050*m + 0.4505937099*s
+  B = 0.0259040371*l + 0.7827717662*m - 0.8086757660*s
+  return
+}
+
+func oklab2linear(L, A, B float64) (r, g, b float64) {
+  l := L + 0.3963377774*A + 0.2158037573*B
+  m := L - 0.1055613458*A - 0.0638541728*B
+  s := L - 0.0894841775*A - 1.2914855480*B
+  l, m, s = l*l*l, m*m*m, s*s*s
+
+  r = +4.0767416621*l - 3.3077115913*m + 0.2309699292*s
+  g = -1.2684380046*l + 2.6097574011*m - 0.3413193965*s
+  b = -0.0041960863*l - 0.7034186147*m + 1.7076147010*s
+  return
+}
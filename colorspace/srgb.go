@@ -0,0 +1,54 @@
+package colorspace
+
+import (
+  "image/color"
+  "math"
+)
+
+// linearize returns the linear sRGB components of c in [0, 1].
+func linearize(c color.Color) (r, g, b float64) {
+  R, G, B := rgb(c)
+  return decode(R), decode(G), decode(B)
+}
+
+// rgb returns the components of c in [0, 1], premultiplied by alpha as in
+// package lab.
+func rgb(c color.Color) (r, g, b float64) {
+  R, G, B, _ := c.RGBA()
+  return float64(R) / 0xffff, float64(G) / 0xffff, float64(B) / 0xffff
+}
+
+// rgb8 returns R, G, B as a color.Color.
+func rgb8(R, G, B uint8) color.Color {
+  return color.RGBA{R, G, B, 0xff}
+}
+
+func decode(v float64) float64 {
+  if v > 0.04045 {
+    return math.Pow((v+0.055)/1.055, 2.4)
+  }
+  return v / 12.92
+}
+
+func encode(v float64) float64 {
+  if v > 0.0031308 {
+    return 1.055*math.Pow(v, 1/2.4) - 0.055
+  }
+  return 12.92 * v
+}
+
+// quantize clamps v to [0, 1] and rounds it to 8 bits.
+func quantize(v float64) uint8 {
+  if v <= 0 {
+    return 0
+  }
+  if v >= 1 {
+    return 255
+  }
+  return uint8(v*255 + 0.5)
+}
+
+// fromLinear encodes and quantizes linear sRGB components.
+func fromLinear(r, g, b float64) (R, G, B uint8) {
+  return quantize(encode(r)), quantize(encode(g)), quantize(encode(b))
+}
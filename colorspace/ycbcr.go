@@ -0,0 +1,62 @@
+package colorspace
+
+import "image/color"
+
+// YCbCr is a full-range JFIF (BT.601) color with every component in
+// [0, 255]. Unlike color.YCbCr the components are not quantized.
+type YCbCr struct {
+  Y, Cb, Cr float64
+}
+
+func (c YCbCr) RGBA() (uint32, uint32, uint32, uint32) {
+  return rgba(Ycbcr2rgb(c.Y, c.Cb, c.Cr))
+}
+
+var YCbCrModel color.Model = color.ModelFunc(ycbcrModel)
+
+func ycbcrModel(c color.Color) color.Color {
+  if _, ok := c.(YCbCr); ok {
+    return c
+  }
+  r, g, b := rgb(c)
+  y, cb, cr := rgb2ycbcr(r*255, g*255, b*255)
+  return YCbCr{y, cb, cr}
+}
+
+func Rgb2ycbcr(R, G, B uint8) (y, cb, cr float64) {
+  return rgb2ycbcr(float64(R), float64(G), float64(B))
+}
+
+func Ycbcr2rgb(y, cb, cr float64) (R, G, B uint8) {
+  cb -= 128
+  cr -= 128
+  r := y + 1.402*cr
+  g := y - 0.344136*cb - 0.714136*cr
+  b := y + 1.772*cb
+  return quantize(r / 255), quantize(g / 255), quantize(b / 255)
+}
+
+// YCbCrSpace is YCbCr scaled to [0, 100] for Y and centred on zero for Cb and
+// Cr.
+var YCbCrSpace Space = ycbcrSpace{}
+
+type ycbcrSpace struct{}
+
+func (ycbcrSpace) Name() string { return "ycbcr" }
+
+func (ycbcrSpace) FromColor(c color.Color) (x, y, z float64) {
+  r, g, b := rgb(c)
+  Y, cb, cr := rgb2ycbcr(r*255, g*255, b*255)
+  return Y * 100 / 255, (cb - 128) * 100 / 255, (cr - 128) * 100 / 255
+}
+
+func (ycbcrSpace) ToRGB(x, y, z float64) (R, G, B uint8) {
+  return Ycbcr2rgb(x*255/100, y*255/100+128, z*255/100+128)
+}
+
+func rgb2ycbcr(r, g, b float64) (y, cb, cr float64) {
+  y = 0.299*r + 0.587*g + 0.114*b
+  cb = 128 - 0.168736*r - 0.331264*g + 0.5*b
+  cr = 128 + 0.5*r - 0.418688*g - 0.081312*b
+  return
+}
@@ -0,0 +1,161 @@
+package slic
+
+import (
+  "math"
+  "sort"
+)
+
+// Connectivity chooses which pixels count as touching when clusters are split
+// into connected superpixels after Run.
+type Connectivity int
+
+const (
+  // Connect4 joins pixels that share an edge.
+  Connect4 Connectivity = iota
+  // Connect8 also joins pixels that only share a corner, so diagonal strands
+  // stay part of their superpixel.
+  Connect8
+)
+
+// Merge chooses which neighbouring superpixel absorbs a fragment smaller than
+// the minimum size.
+type Merge int
+
+const (
+  // MergeAdjacent merges a fragment into the last superpixel found next to
+  // its first pixel in raster order. It is the fastest policy.
+  MergeAdjacent Merge = iota
+  // MergeClosestColor merges a fragment into the neighbouring superpixel
+  // whose mean color is closest to its own. Fragments are merged smallest
+  // first, and a superpixel that has grown past the minimum size by
+  // absorbing fragments is kept.
+  MergeClosestColor
+)
+
+func neighbours(c Connectivity) (dx, dy []int) {
+  if c == Connect8 {
+    return []int{-1, -1, 0, 1, 1, 1, 0, -1}, []int{0, -1, -1, -1, 0, 1, 1, 1}
+  }
+  return []int{-1, 0, 1, 0}, []int{0, -1, 0, 1}
+}
+
+// minSize returns the smallest superpixel, in pixels, that connectivity
+// enforcement keeps, given the expected superpixel area.
+func (slic *SLIC) minSize(area int) int {
+  switch {
+  case slic.minsize < 0:
+    return 0
+  case slic.minsize > 0:
+    return slic.minsize
+  case slic.minsizeRatio > 0:
+    return int(slic.minsizeRatio*float64(area)) + 1
+  }
+  return area>>2 + 1
+}
+
+// mergeClosestColor merges the regions of labels smaller than minsize into
+// the neighbour closest in mean color, then renumbers the survivors in raster
+// order of first pixel. It returns the same values as
+// enforceLabelConnectivity.
+func (slic *SLIC) mergeClosestColor(n int, labels, origins []int, minsize int) (int, []int, []int) {
+  if minsize <= 1 || n <= 1 {
+    return n, labels, origins
+  }
+  size := slic.image.Bounds().Size()
+  width, height := size.X, size.Y
+  dx, dy := neighbours(slic.connectivity)
+
+  count := make([]int, n)
+  sigma_l := make([]float64, n)
+  sigma_a := make([]float64, n)
+  sigma_b := make([]float64, n)
+  adjacent := make([]map[int]bool, n)
+  for i := range adjacent {
+    adjacent[i] = make(map[int]bool)
+  }
+
+  for y := 0; y < height; y++ {
+    for x := 0; x < width; x++ {
+      label := labels[y*width+x]
+      c := slic.image.LabAt(x, y)
+      sigma_l[label] += c.L
+      sigma_a[label] += c.A
+      sigma_b[label] += c.B
+      count[label]++
+      for k := range dx {
+        nx, ny := x+dx[k], y+dy[k]
+        if (nx >= 0 && nx < width) && (ny >= 0 && ny < height) {
+          if nlabel := labels[ny*width+nx]; nlabel != label {
+            adjacent[label][nlabel] = true
+          }
+        }
+      }
+    }
+  }
+
+  parent := make([]int, n)
+  for i := range parent {
+    parent[i] = i
+  }
+  var find func(int) int
+  find = func(i int) int {
+    if parent[i] != i {
+      parent[i] = find(parent[i])
+    }
+    return parent[i]
+  }
+
+  order := make([]int, n)
+  for i := range order {
+    order[i] = i
+  }
+  sort.SliceStable(order, func(i, j int) bool { return count[order[i]] < count[order[j]] })
+
+  for _, r := range order {
+    if count[r] >= minsize {
+      continue
+    }
+    fc := float64(count[r])
+    l, a, b := sigma_l[r]/fc, sigma_a[r]/fc, sigma_b[r]/fc
+    best, bestdist := -1, math.MaxFloat64
+    for nb := range adjacent[r] {
+      nr := find(nb)
+      if nr == r {
+        continue
+      }
+      nc := float64(count[nr])
+      dl, da, db := sigma_l[nr]/nc-l, sigma_a[nr]/nc-a, sigma_b[nr]/nc-b
+      dist := dl*dl + da*da + db*db
+      // Break ties by label so the result does not depend on map order.
+      if dist < bestdist || dist == bestdist && nr < best {
+        best, bestdist = nr, dist
+      }
+    }
+    if best < 0 {
+      continue
+    }
+    parent[r] = best
+    count[best] += count[r]
+    sigma_l[best] += sigma_l[r]
+    sigma_a[best] += sigma_a[r]
+    sigma_b[best] += sigma_b[r]
+    for nb := range adjacent[r] {
+      adjacent[best][nb] = true
+    }
+  }
+
+  renumber := make([]int, n)
+  for i := range renumber {
+    renumber[i] = -1
+  }
+  var norigins []int
+  for i, label := range labels {
+    r := find(label)
+    if renumber[r] < 0 {
+      renumber[r] = len(norigins)
+      norigins = append(norigins, origins[r])
+    }
+    labels[i] = renumber[r]
+  }
+  return len(norigins), labels, norigins
+}
@@ -0,0 +1,271 @@
+package slic
+
+import (
+  "image"
+  "image/color"
+  "image/draw"
+)
+
+type EdgePlacement int
+
+const (
+  // EdgeThin draws a one pixel contour between superpixels, on whichever side
+  // of the boundary is reached first.
+  EdgeThin EdgePlacement = iota
+  // EdgeInner draws every superpixel's border on its own outermost pixels.
+  EdgeInner
+  // EdgeOuter draws every superpixel's border on the neighbouring pixels just
+  // outside it.
+  EdgeOuter
+)
+
+// DrawOptions controls how DrawEdges renders superpixel boundaries. Colors are
+// composited over the source image, so a translucent color blends with it.
+type DrawOptions struct {
+  // Color is the boundary color. Defaults to opaque black.
+  Color color.Color
+  // LabelColor, when set, chooses the boundary color per label instead of
+  // Color. With EdgeOuter the label is that of the superpixel being outlined,
+  // not the one the pixel belongs to.
+  LabelColor func(label int) color.Color
+  // Unlabeled is drawn over pixels that were never assigned a label. Defaults
+  // to opaque red.
+  Unlabeled color.Color
+  // Thickness of boundaries in pixels. Boundaries thicker than one pixel are
+  // dilated without crossing into the superpixel on the other side for
+  // EdgeInner, or into the outlined superpixel for EdgeOuter.
+  Thickness int
+  Placement EdgePlacement
+}
+
+const noEdge = -2
+
+func (slic *SLIC) DrawEdgesToImage(img image.Image) *image.RGBA {
+  return slic.DrawEdges(img, nil)
+}
+
+// DrawEdges returns a copy of img, in img's coordinate space, with superpixel
+// boundaries drawn over it. A nil opts uses the defaults.
+func (slic *SLIC) DrawEdges(img image.Image, opts *DrawOptions) *image.RGBA {
+  var o DrawOptions
+  if opts != nil {
+    o = *opts
+  }
+  if o.Color == nil {
+    o.Color = color.RGBA{0, 0, 0, 255}
+  }
+  if o.Unlabeled == nil {
+    o.Unlabeled = color.RGBA{255, 0, 0, 255}
+  }
+  if o.Thickness < 1 {
+    o.Thickness = 1
+  }
+
+  b := img.Bounds()
+  canvas := image.NewRGBA(b)
+  draw.Draw(canvas, b, img, b.Min, draw.Src)
+
+  size := slic.image.Bounds().Size()
+  width, height := size.X, size.Y
+
+  var edges []int
+  switch o.Placement {
+  case EdgeInner:
+    edges = slic.innerEdges()
+  case EdgeOuter:
+    edges = slic.outerEdges()
+  default:
+    edges = slic.thinEdges()
+  }
+  if o.Thickness > 1 {
+    edges = slic.dilateEdges(edges, o.Thickness, o.Placement)
+  }
+
+  for j := 0; j < height; j++ {
+    for k := 0; k < width; k++ {
+      i := j*width + k
+      var c color.Color
+      switch {
+      case slic.Labels[i] == -1:
+        c = o.Unlabeled
+      case edges[i] == noEdge:
+        continue
+      case o.LabelColor != nil:
+        c = o.LabelColor(edges[i])
+      default:
+        c = o.Color
+      }
+      blend(canvas, b.Min.X+k, b.Min.Y+j, c)
+    }
+  }
+
+  return canvas
+}
+
+// thinEdges marks pixels with more than one differently labeled 8-neighbour
+// that is not itself already part of a contour.
+func (slic *SLIC) thinEdges() []int {
+  dx8 := []int{-1, -1, 0, 1, 1, 1, 0, -1}
+  dy8 := []int{0, -1, -1, -1, 0, 1, 1, 1}
+
+  size := slic.image.Bounds().Size()
+  width, height := size.X, size.Y
+  edges := newEdges(width * height)
+  istaken := make([]bool, width*height)
+  mainindex := 0
+
+  for j := 0; j < height; j++ {
+    for k := 0; k < width; k++ {
+      if slic.Labels[mainindex] == -1 {
+        mainindex++
+        continue
+      }
+
+      np := 0
+      for i := 0; i < 8; i++ {
+        x := k + dx8[i]
+        y := j + dy8[i]
+
+        if (x >= 0 && x < width) && (y >= 0 && y < height) {
+          index := y*width + x
+          if !istaken[index] {
+            if slic.Labels[mainindex] != slic.Labels[index] {
+              np++
+            }
+          }
+        }
+      }
+      if np > 1 {
+        edges[mainindex] = slic.Labels[mainindex]
+        istaken[mainindex] = true
+      }
+      mainindex++
+    }
+  }
+
+  return edges
+}
+
+func (slic *SLIC) innerEdges() []int {
+  size := slic.image.Bounds().Size()
+  width, height := size.X, size.Y
+  edges := newEdges(width * height)
+
+  for j := 0; j < height; j++ {
+    for k := 0; k < width; k++ {
+      i := j*width + k
+      label := slic.Labels[i]
+      if label == -1 {
+        continue
+      }
+      if _, ok := slic.differentNeighbour(k, j); ok {
+        edges[i] = label
+      }
+    }
+  }
+
+  return edges
+}
+
+func (slic *SLIC) outerEdges() []int {
+  size := slic.image.Bounds().Size()
+  width, height := size.X, size.Y
+  edges := newEdges(width * height)
+
+  for j := 0; j < height; j++ {
+    for k := 0; k < width; k++ {
+      if label, ok := slic.differentNeighbour(k, j); ok {
+        edges[j*width+k] = label
+      }
+    }
+  }
+
+  return edges
+}
+
+// differentNeighbour returns the label of the first labeled 4-neighbour of
+// (x, y) whose label differs from the pixel's own.
+func (slic *SLIC) differentNeighbour(x, y int) (int, bool) {
+  dx4 := [...]int{-1, 0, 1, 0}
+  dy4 := [...]int{0, -1, 0, 1}
+
+  size := slic.image.Bounds().Size()
+  width, height := size.X, size.Y
+  label := slic.Labels[y*width+x]
+
+  for n := 0; n < 4; n++ {
+    nx, ny := x+dx4[n], y+dy4[n]
+    if (nx >= 0 && nx < width) && (ny >= 0 && ny < height) {
+      nlabel := slic.Labels[ny*width+nx]
+      if nlabel != label && nlabel != -1 {
+        return nlabel, true
+      }
+    }
+  }
+  return 0, false
+}
+
+func (slic *SLIC) dilateEdges(edges []int, thickness int, placement EdgePlacement) []int {
+  size := slic.image.Bounds().Size()
+  width, height := size.X, size.Y
+  lo, hi := -(thickness-1)/2, thickness/2
+
+  out := make([]int, len(edges))
+  copy(out, edges)
+
+  for j := 0; j < height; j++ {
+    for k := 0; k < width; k++ {
+      i := j*width + k
+      label := edges[i]
+      if label == noEdge {
+        continue
+      }
+      for dy := lo; dy <= hi; dy++ {
+        for dx := lo; dx <= hi; dx++ {
+          x, y := k+dx, j+dy
+          if x < 0 || x >= width || y < 0 || y >= height {
+            continue
+          }
+          n := y*width + x
+          if out[n] != noEdge {
+            continue
+          }
+          if placement == EdgeInner && slic.Labels[n] != slic.Labels[i] {
+            continue
+          }
+          if placement == EdgeOuter && slic.Labels[n] == label {
+            continue
+          }
+          out[n] = label
+        }
+      }
+    }
+  }
+
+  return out
+}
+
+func newEdges(sz int) []int {
+  edges := make([]int, sz)
+  for i := range edges {
+    edges[i] = noEdge
+  }
+  return edges
+}
+
+// blend composites c over the pixel at (x, y).
+func blend(canvas *image.RGBA, x, y int, c color.Color) {
+  sr, sg, sb, sa := c.RGBA()
+  if sa == 0xffff {
+    canvas.Set(x, y, c)
+    return
+  }
+  d := canvas.RGBAAt(x, y)
+  a := 0xffff - sa
+  canvas.SetRGBA(x, y, color.RGBA{
+    uint8((sr + uint32(d.R)*0x101*a/0xffff) >> 8),
+    uint8((sg + uint32(d.G)*0x101*a/0xffff) >> 8),
+    uint8((sb + uint32(d.B)*0x101*a/0xffff) >> 8),
+    uint8((sa + uint32(d.A)*0x101*a/0xffff) >> 8),
+  })
+}
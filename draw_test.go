@@ -0,0 +1,98 @@
+package slic
+
+import (
+  "image"
+  "image/color"
+  "testing"
+
+  . "github.com/franela/goblin"
+)
+
+var (
+  drawGray  = color.RGBA{100, 100, 100, 255}
+  drawRed   = color.RGBA{255, 0, 0, 255}
+  drawBlue  = color.RGBA{0, 0, 255, 255}
+  drawGreen = color.RGBA{0, 255, 0, 255}
+)
+
+// grayHalves is halves over a flat gray image.
+func grayHalves(origin image.Point) (*SLIC, *image.RGBA) {
+  return halves(origin, func(x, y int) color.RGBA { return drawGray })
+}
+
+func labelColor(label int) color.Color {
+  if label == 0 {
+    return drawRed
+  }
+  return drawBlue
+}
+
+// columns returns the color of every column of row y of out.
+func columns(out *image.RGBA, y int) []color.RGBA {
+  b := out.Bounds()
+  var cs []color.RGBA
+  for x := b.Min.X; x < b.Max.X; x++ {
+    cs = append(cs, out.RGBAAt(x, b.Min.Y+y))
+  }
+  return cs
+}
+
+func TestDrawEdges(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("DrawEdges", func() {
+    g.It("Draws inner edges on each superpixel's own pixels", func() {
+      s, img := grayHalves(image.Point{})
+      out := s.DrawEdges(img, &DrawOptions{Placement: EdgeInner, LabelColor: labelColor})
+      for y := 0; y < 4; y++ {
+        g.Assert(columns(out, y)).Equal([]color.RGBA{drawGray, drawGray, drawRed, drawBlue, drawGray, drawGray})
+      }
+    })
+    g.It("Draws outer edges on the neighbouring pixels", func() {
+      s, img := grayHalves(image.Point{})
+      out := s.DrawEdges(img, &DrawOptions{Placement: EdgeOuter, LabelColor: labelColor})
+      for y := 0; y < 4; y++ {
+        g.Assert(columns(out, y)).Equal([]color.RGBA{drawGray, drawGray, drawBlue, drawRed, drawGray, drawGray})
+      }
+    })
+    g.It("Dilates thick edges without crossing into the wrong superpixel", func() {
+      s, img := grayHalves(image.Point{})
+      inner := s.DrawEdges(img, &DrawOptions{Placement: EdgeInner, LabelColor: labelColor, Thickness: 3})
+      outer := s.DrawEdges(img, &DrawOptions{Placement: EdgeOuter, LabelColor: labelColor, Thickness: 3})
+      for y := 0; y < 4; y++ {
+        // Inner edges stay on their own side of the boundary.
+        g.Assert(columns(inner, y)).Equal([]color.RGBA{drawGray, drawRed, drawRed, drawBlue, drawBlue, drawGray})
+        // Outer edges never cover the superpixel they outline.
+        g.Assert(columns(outer, y)).Equal([]color.RGBA{drawGray, drawBlue, drawBlue, drawRed, drawRed, drawGray})
+      }
+    })
+    g.It("Uses the single color unless LabelColor is set", func() {
+      s, img := grayHalves(image.Point{})
+      out := s.DrawEdges(img, &DrawOptions{Placement: EdgeInner, Color: drawGreen})
+      g.Assert(columns(out, 0)).Equal([]color.RGBA{drawGray, drawGray, drawGreen, drawGreen, drawGray, drawGray})
+    })
+    g.It("Draws unlabeled pixels", func() {
+      s, img := grayHalves(image.Point{})
+      s.Labels[0] = -1
+      out := s.DrawEdges(img, &DrawOptions{Unlabeled: drawGreen})
+      g.Assert(out.RGBAAt(0, 0)).Equal(drawGreen)
+      g.Assert(out.RGBAAt(0, 1)).Equal(drawGray)
+    })
+    g.It("Blends translucent colors over the image", func() {
+      s, img := grayHalves(image.Point{})
+      // Half transparent blue, premultiplied.
+      out := s.DrawEdges(img, &DrawOptions{Placement: EdgeInner, Color: color.RGBA{0, 0, 128, 128}})
+      c := out.RGBAAt(2, 0)
+      near := func(v uint8, want int) bool { return int(v) >= want-1 && int(v) <= want+1 }
+      g.Assert(near(c.R, 50) && near(c.G, 50) && near(c.B, 178) && c.A == 255).IsTrue()
+      g.Assert(out.RGBAAt(0, 0)).Equal(drawGray)
+    })
+    g.It("Keeps the bounds of images not anchored at the origin", func() {
+      s, img := grayHalves(image.Pt(10, 20))
+      out := s.DrawEdges(img, &DrawOptions{Placement: EdgeInner, LabelColor: labelColor})
+      g.Assert(out.Bounds()).Equal(img.Bounds())
+      g.Assert(out.RGBAAt(12, 21)).Equal(drawRed)
+      g.Assert(out.RGBAAt(13, 21)).Equal(drawBlue)
+      g.Assert(out.RGBAAt(10, 21)).Equal(drawGray)
+    })
+  })
+}
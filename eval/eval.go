@@ -0,0 +1,302 @@
+// Package eval measures the quality of a superpixel segmentation, either
+// against a ground truth segmentation or against the image it was computed
+// from. Label maps are row-major slices of width*height labels, as in
+// SLIC.Labels; ground truth pixels with negative labels are ignored.
+//
+// The metrics expect label maps and images of matching sizes and return NaN
+// when they are given anything else; Evaluate checks the sizes and returns
+// ErrSize instead.
+package eval
+
+import (
+  "errors"
+  "image"
+  "math"
+
+  "github.com/kurige/SLIC/lab"
+)
+
+var ErrSize = errors.New("eval: label map or image size does not match width*height")
+
+type Report struct {
+  Superpixels            int     `json:"superpixels"`
+  BoundaryRecall         float64 `json:"boundary_recall"`
+  UndersegmentationError float64 `json:"undersegmentation_error"`
+  AchievableAccuracy     float64 `json:"achievable_accuracy"`
+  ExplainedVariation     float64 `json:"explained_variation"`
+  Compactness            float64 `json:"compactness"`
+}
+
+// Evaluate computes every metric for labels. Truth may be nil, in which case
+// only the metrics that do not need ground truth are filled in; likewise img
+// may be nil to skip ExplainedVariation. It returns ErrSize if the label maps
+// or img do not have the given dimensions.
+func Evaluate(labels, truth []int, width, height int, img image.Image, tolerance int) (Report, error) {
+  var r Report
+  if len(labels) != width*height || (truth != nil && len(truth) != width*height) {
+    return r, ErrSize
+  }
+  if img != nil && img.Bounds().Size() != image.Pt(width, height) {
+    return r, ErrSize
+  }
+
+  r.Superpixels = countLabels(labels)
+  r.Compactness = Compactness(labels, width, height)
+  if truth != nil {
+    r.BoundaryRecall = BoundaryRecall(labels, truth, width, height, tolerance)
+    r.UndersegmentationError = UndersegmentationError(labels, truth)
+    r.AchievableAccuracy = AchievableSegmentationAccuracy(labels, truth)
+  }
+  if img != nil {
+    r.ExplainedVariation = ExplainedVariation(labels, img)
+  }
+  return r, nil
+}
+
+// BoundaryRecall returns the fraction of ground truth boundary pixels that lie
+// within tolerance pixels (in each direction) of a superpixel boundary.
+func BoundaryRecall(labels, truth []int, width, height, tolerance int) float64 {
+  if !fits(labels, width, height) || !fits(truth, width, height) {
+    return math.NaN()
+  }
+  sp := boundaries(labels, width, height, false)
+  gt := boundaries(truth, width, height, true)
+
+  var hits, total int
+  for y := 0; y < height; y++ {
+    for x := 0; x < width; x++ {
+      if !gt[y*width+x] {
+        continue
+      }
+      total++
+      if nearBoundary(sp, width, height, x, y, tolerance) {
+        hits++
+      }
+    }
+  }
+  if total == 0 {
+    return 1
+  }
+  return float64(hits) / float64(total)
+}
+
+// UndersegmentationError is the corrected undersegmentation error of Neubert
+// and Protzel: every superpixel overlapping a ground truth segment is charged
+// the smaller of its part inside and its part outside the segment.
+func UndersegmentationError(labels, truth []int) float64 {
+  if len(labels) != len(truth) {
+    return math.NaN()
+  }
+  overlap, sizes, n := contingency(labels, truth)
+  if n == 0 {
+    return 0
+  }
+
+  var err int
+  for k, count := range overlap {
+    outside := sizes[k.superpixel] - count
+    if outside < count {
+      err += outside
+    } else {
+      err += count
+    }
+  }
+  return float64(err) / float64(n)
+}
+
+// AchievableSegmentationAccuracy is the fraction of pixels labeled correctly
+// when every superpixel is assigned to the ground truth segment it overlaps
+// most.
+func AchievableSegmentationAccuracy(labels, truth []int) float64 {
+  if len(labels) != len(truth) {
+    return math.NaN()
+  }
+  overlap, _, n := contingency(labels, truth)
+  if n == 0 {
+    return 0
+  }
+
+  best := make(map[int]int)
+  for k, count := range overlap {
+    if count > best[k.superpixel] {
+      best[k.superpixel] = count
+    }
+  }
+  var correct int
+  for _, count := range best {
+    correct += count
+  }
+  return float64(correct) / float64(n)
+}
+
+// ExplainedVariation is the fraction of the image's Lab color variance that is
+// explained by replacing each pixel with its superpixel's mean color.
+func ExplainedVariation(labels []int, img image.Image) float64 {
+  b := img.Bounds()
+  width, height := b.Dx(), b.Dy()
+  if !fits(labels, width, height) {
+    return math.NaN()
+  }
+
+  type sum struct {
+    l, a, b float64
+    n       int
+  }
+  sums := make(map[int]*sum)
+  colors := make([]lab.Color, width*height)
+  var mean lab.Color
+
+  for y := 0; y < height; y++ {
+    for x := 0; x < width; x++ {
+      i := y*width + x
+      c := lab.ColorModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(lab.Color)
+      colors[i] = c
+      mean.L += c.L
+      mean.A += c.A
+      mean.B += c.B
+
+      s := sums[labels[i]]
+      if s == nil {
+        s = &sum{}
+        sums[labels[i]] = s
+      }
+      s.l += c.L
+      s.a += c.A
+      s.b += c.B
+      s.n++
+    }
+  }
+  n := float64(width * height)
+  mean.L /= n
+  mean.A /= n
+  mean.B /= n
+
+  var total float64
+  for _, c := range colors {
+    total += sqdist(c, mean)
+  }
+  if total == 0 {
+    return 1
+  }
+
+  var explained float64
+  for _, s := range sums {
+    count := float64(s.n)
+    c := lab.Color{L: s.l / count, A: s.a / count, B: s.b / count}
+    explained += count * sqdist(c, mean)
+  }
+  return explained / total
+}
+
+// Compactness is the area weighted isoperimetric quotient 4πA/P² of the
+// superpixels, where the perimeter counts pixel edges on the superpixel's
+// border, including edges on the image border.
+func Compactness(labels []int, width, height int) float64 {
+  if !fits(labels, width, height) {
+    return math.NaN()
+  }
+  area := make(map[int]int)
+  perimeter := make(map[int]int)
+
+  for y := 0; y < height; y++ {
+    for x := 0; x < width; x++ {
+      l := labels[y*width+x]
+      area[l]++
+      if x == 0 || labels[y*width+x-1] != l {
+        perimeter[l]++
+      }
+      if x == width-1 || labels[y*width+x+1] != l {
+        perimeter[l]++
+      }
+      if y == 0 || labels[(y-1)*width+x] != l {
+        perimeter[l]++
+      }
+      if y == height-1 || labels[(y+1)*width+x] != l {
+        perimeter[l]++
+      }
+    }
+  }
+
+  n := float64(width * height)
+  var co float64
+  for l, a := range area {
+    p := float64(perimeter[l])
+    co += float64(a) / n * 4 * math.Pi * float64(a) / (p * p)
+  }
+  return co
+}
+
+// fits reports whether labels is a width*height label map.
+func fits(labels []int, width, height int) bool {
+  return width >= 0 && height >= 0 && len(labels) == width*height
+}
+
+type pair struct {
+  superpixel, segment int
+}
+
+// contingency counts the overlap of every superpixel and ground truth segment,
+// along with superpixel sizes, over the pixels with a ground truth label.
+func contingency(labels, truth []int) (overlap map[pair]int, sizes map[int]int, n int) {
+  overlap = make(map[pair]int)
+  sizes = make(map[int]int)
+  for i, s := range truth {
+    if s < 0 {
+      continue
+    }
+    overlap[pair{labels[i], s}]++
+    sizes[labels[i]]++
+    n++
+  }
+  return
+}
+
+// boundaries marks pixels with a 4-neighbour of a different label. With
+// ignore set, pixels with negative labels are neither boundaries nor make
+// their neighbours one.
+func boundaries(labels []int, width, height int, ignore bool) []bool {
+  edges := make([]bool, width*height)
+  differs := func(l, n int) bool {
+    return n != l && !(ignore && n < 0)
+  }
+  for y := 0; y < height; y++ {
+    for x := 0; x < width; x++ {
+      i := y*width + x
+      l := labels[i]
+      if ignore && l < 0 {
+        continue
+      }
+      if (x > 0 && differs(l, labels[i-1])) || (x < width-1 && differs(l, labels[i+1])) ||
+        (y > 0 && differs(l, labels[i-width])) || (y < height-1 && differs(l, labels[i+width])) {
+        edges[i] = true
+      }
+    }
+  }
+  return edges
+}
+
+func nearBoundary(edges []bool, width, height, x, y, r int) bool {
+  for j := y - r; j <= y+r; j++ {
+    for k := x - r; k <= x+r; k++ {
+      if j < 0 || j >= height || k < 0 || k >= width {
+        continue
+      }
+      if edges[j*width+k] {
+        return true
+      }
+    }
+  }
+  return false
+}
+
+func countLabels(labels []int) int {
+  seen := make(map[int]bool)
+  for _, l := range labels {
+    seen[l] = true
+  }
+  return len(seen)
+}
+
+func sqdist(c1, c2 lab.Color) float64 {
+  return (c1.L-c2.L)*(c1.L-c2.L) + (c1.A-c2.A)*(c1.A-c2.A) + (c1.B-c2.B)*(c1.B-c2.B)
+}
@@ -0,0 +1,104 @@
+package eval
+
+import (
+  "image"
+  "image/color"
+  "math"
+  "testing"
+
+  . "github.com/franela/goblin"
+)
+
+// Two 2x2 superpixels over a ground truth split one column further right.
+var (
+  LABELS = []int{
+    0, 0, 1, 1,
+    0, 0, 1, 1,
+  }
+  TRUTH = []int{
+    0, 0, 0, 1,
+    0, 0, 0, 1,
+  }
+)
+
+const WIDTH, HEIGHT = 4, 2
+
+func TestMetrics(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("Metrics", func() {
+    g.It("Boundary recall", func() {
+      g.Assert(BoundaryRecall(LABELS, TRUTH, WIDTH, HEIGHT, 0)).Equal(0.5)
+      g.Assert(BoundaryRecall(LABELS, TRUTH, WIDTH, HEIGHT, 1)).Equal(1.0)
+      g.Assert(BoundaryRecall(LABELS, LABELS, WIDTH, HEIGHT, 0)).Equal(1.0)
+    })
+    g.It("Ignores boundaries of unlabeled ground truth", func() {
+      labels := []int{0, 0, 0, 1, 1, 1, 0, 0, 0, 1, 1, 1}
+      truth := []int{0, 0, 0, 1, -1, -1, 0, 0, 0, 1, -1, -1}
+      g.Assert(BoundaryRecall(labels, truth, 6, 2, 0)).Equal(1.0)
+      // Without the ignored region, the edge at x=4 is missed.
+      truth = []int{0, 0, 0, 1, 2, 2, 0, 0, 0, 1, 2, 2}
+      g.Assert(BoundaryRecall(labels, truth, 6, 2, 0)).Equal(4.0 / 6)
+    })
+    g.It("Undersegmentation error", func() {
+      g.Assert(UndersegmentationError(LABELS, TRUTH)).Equal(0.5)
+      g.Assert(UndersegmentationError(LABELS, LABELS)).Equal(0.0)
+    })
+    g.It("Achievable segmentation accuracy", func() {
+      g.Assert(AchievableSegmentationAccuracy(LABELS, TRUTH)).Equal(0.75)
+      g.Assert(AchievableSegmentationAccuracy(LABELS, LABELS)).Equal(1.0)
+    })
+    g.It("Ignores unlabeled ground truth", func() {
+      truth := []int{0, 0, -1, 1, 0, 0, -1, 1}
+      g.Assert(AchievableSegmentationAccuracy(LABELS, truth)).Equal(1.0)
+    })
+    g.It("Compactness", func() {
+      g.Assert(Compactness(LABELS, WIDTH, HEIGHT)).Equal(math.Pi / 4)
+    })
+    g.It("Explained variation", func() {
+      img := image.NewRGBA(image.Rect(0, 0, WIDTH, HEIGHT))
+      for y := 0; y < HEIGHT; y++ {
+        for x := 0; x < WIDTH; x++ {
+          if x < 2 {
+            img.Set(x, y, color.RGBA{200, 20, 20, 255})
+          } else {
+            img.Set(x, y, color.RGBA{20, 20, 200, 255})
+          }
+        }
+      }
+      g.Assert(math.Abs(ExplainedVariation(LABELS, img)-1) < 1e-9).IsTrue()
+      g.Assert(ExplainedVariation(make([]int, WIDTH*HEIGHT), img)).Equal(0.0)
+    })
+    g.It("Return NaN for mismatched sizes", func() {
+      short := LABELS[:WIDTH*HEIGHT-1]
+      g.Assert(math.IsNaN(BoundaryRecall(short, TRUTH, WIDTH, HEIGHT, 0))).IsTrue()
+      g.Assert(math.IsNaN(BoundaryRecall(LABELS, short, WIDTH, HEIGHT, 0))).IsTrue()
+      g.Assert(math.IsNaN(UndersegmentationError(short, TRUTH))).IsTrue()
+      g.Assert(math.IsNaN(AchievableSegmentationAccuracy(LABELS, short))).IsTrue()
+      g.Assert(math.IsNaN(Compactness(short, WIDTH, HEIGHT))).IsTrue()
+      img := image.NewRGBA(image.Rect(0, 0, WIDTH, HEIGHT+1))
+      g.Assert(math.IsNaN(ExplainedVariation(LABELS, img))).IsTrue()
+    })
+  })
+}
+
+func TestEvaluate(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("Evaluate", func() {
+    g.It("Fills in the report", func() {
+      r, err := Evaluate(LABELS, TRUTH, WIDTH, HEIGHT, nil, 0)
+      g.Assert(err).Equal(nil)
+      g.Assert(r.Superpixels).Equal(2)
+      g.Assert(r.BoundaryRecall).Equal(0.5)
+      g.Assert(r.UndersegmentationError).Equal(0.5)
+      g.Assert(r.AchievableAccuracy).Equal(0.75)
+      g.Assert(r.Compactness).Equal(math.Pi / 4)
+    })
+    g.It("Rejects mismatched sizes", func() {
+      _, err := Evaluate(LABELS, TRUTH[:3], WIDTH, HEIGHT, nil, 0)
+      g.Assert(err).Equal(ErrSize)
+      img := image.NewRGBA(image.Rect(0, 0, WIDTH+1, HEIGHT))
+      _, err = Evaluate(LABELS, TRUTH, WIDTH, HEIGHT, img, 0)
+      g.Assert(err).Equal(ErrSize)
+    })
+  })
+}
@@ -14,7 +14,7 @@ import (
   "runtime/pprof"
 
   "github.com/kurige/SLIC"
-  "github.com/kurige/SLIC/lab"
+  _ "github.com/kurige/SLIC/netpbm"
 )
 
 type handlerFunc func(*os.File)
@@ -29,6 +29,7 @@ var (
   superpixelsize = flag.Int("size", 40, "Super pixel size")
   cpuprofile     = flag.String("cpuprofile", "", "write cpu profile to file")
   compactness    = flag.Float64("c", 20.0, "Superpixel 'compactness'")
+  mode           = flag.String("mode", "mean", "Fill superpixels with their mean, median or random color, or mean with centroid dots")
 )
 
 func main() {
@@ -68,20 +69,20 @@ func main() {
   s := slic.MakeSlic(src_img, *compactness, *superpixelsize)
 
   s.Run(10)
-  lvec, avec, bvec := s.AverageColors()
 
-  out := image.NewNRGBA(image.Rect(0, 0, src_w, src_h))
-  for y := 0; y < src_h; y++ {
-    for x := 0; x < src_w; x++ {
-      i := y*src_w + x
-      label := s.Labels[i]
-      l := lvec[label]
-      a := avec[label]
-      b := bvec[label]
-      R, G, B := lab.Lab2rgb(l, a, b)
-      c := color.RGBA{R, G, B, 255}
-      out.Set(x, y, c)
-    }
+  var out image.Image
+  switch *mode {
+  case "mean":
+    out = s.MeanColorImage()
+  case "median":
+    out = s.MedianColorImage()
+  case "random":
+    out = s.RandomColorImage(1)
+  case "dots":
+    out = s.CentroidDots(s.MeanColorImage(), 1, color.RGBA{255, 0, 0, 255})
+  default:
+    log.Println("Unknown mode:", *mode)
+    return
   }
 
   outputPNG(out, "out.png")
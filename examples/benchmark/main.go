@@ -0,0 +1,324 @@
+package main
+
+import (
+  "encoding/csv"
+  "encoding/json"
+  "flag"
+  "fmt"
+  "image"
+  _ "image/gif"
+  _ "image/jpeg"
+  _ "image/png"
+  "io"
+  "io/fs"
+  "log"
+  "os"
+  "path/filepath"
+  "runtime"
+  "strconv"
+  "strings"
+  "time"
+
+  "github.com/kurige/SLIC"
+  "github.com/kurige/SLIC/eval"
+  "github.com/kurige/SLIC/labelio"
+  _ "github.com/kurige/SLIC/netpbm"
+)
+
+var (
+  imageDir    = flag.String("images", ".", "Directory of input images")
+  truthDir    = flag.String("truth", "", "Directory of ground truth label maps (defaults to -images)")
+  counts      = flag.String("pixels", "100,200,400,800", "Comma separated superpixel counts to sweep")
+  compactness = flag.String("c", "10,20,40", "Comma separated compactness values to sweep")
+  iterations  = flag.Int("i", 10, "Number of iterations")
+  tolerance   = flag.Int("tolerance", 2, "Boundary recall tolerance in pixels")
+  output      = flag.String("o", "", "Output file (defaults to stdout)")
+  format      = flag.String("format", "csv", "Output format: csv or json")
+  perImage    = flag.Bool("per-image", false, "Write one row per image instead of per-configuration means")
+)
+
+var imageExts = map[string]bool{
+  ".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
+  ".pgm": true, ".ppm": true, ".pnm": true, ".pam": true,
+}
+
+// Ground truth files share the image's base name. BSDS .seg files are
+// preferred, then the formats understood by labelio.Load.
+var truthExts = []string{".seg", ".png", ".npy", ".raw"}
+
+// sample names an image and its ground truth. They are loaded one image at a
+// time, so a large data set never has to fit in memory.
+type sample struct {
+  name      string
+  path      string
+  truthPath string
+}
+
+type loaded struct {
+  name   string
+  img    image.Image
+  truth  []int
+  width  int
+  height int
+}
+
+type result struct {
+  Image       string      `json:"image,omitempty"`
+  Images      int         `json:"images"`
+  Requested   int         `json:"requested"`
+  Compactness float64     `json:"compactness"`
+  Iterations  int         `json:"iterations"`
+  Seconds     float64     `json:"seconds"`
+  Metrics     eval.Report `json:"metrics"`
+}
+
+func main() {
+  flag.Parse()
+  runtime.GOMAXPROCS(runtime.NumCPU())
+
+  countList, err := parseInts(*counts)
+  if err != nil {
+    log.Fatal("Bad -pixels: ", err)
+  }
+  compactnessList, err := parseFloats(*compactness)
+  if err != nil {
+    log.Fatal("Bad -c: ", err)
+  }
+
+  if *truthDir == "" {
+    *truthDir = *imageDir
+  }
+
+  samples, err := findSamples(*imageDir, *truthDir)
+  if err != nil {
+    log.Fatal(err)
+  }
+  if len(samples) == 0 {
+    log.Fatal("No images with ground truth found in ", *imageDir)
+  }
+
+  // Every configuration is run on an image before the next one is loaded.
+  rows := make([][]result, len(countList)*len(compactnessList))
+  for _, s := range samples {
+    l, ok := load(s)
+    if !ok {
+      continue
+    }
+    for i, count := range countList {
+      for j, c := range compactnessList {
+        row, err := run(l, count, c)
+        if err != nil {
+          log.Println(err, "Could not evaluate:", s.name)
+          continue
+        }
+        k := i*len(compactnessList) + j
+        rows[k] = append(rows[k], row)
+      }
+    }
+  }
+
+  var results []result
+  for _, r := range rows {
+    if *perImage {
+      results = append(results, r...)
+    } else if len(r) > 0 {
+      results = append(results, mean(r))
+    }
+  }
+
+  out := os.Stdout
+  if *output != "" {
+    if out, err = os.Create(*output); err != nil {
+      log.Fatal(err)
+    }
+    defer out.Close()
+  }
+
+  switch *format {
+  case "csv":
+    err = writeCSV(out, results)
+  case "json":
+    enc := json.NewEncoder(out)
+    enc.SetIndent("", "  ")
+    err = enc.Encode(results)
+  default:
+    err = fmt.Errorf("unknown format %q", *format)
+  }
+  if err != nil {
+    log.Fatal(err)
+  }
+}
+
+// findSamples lists the images under imageDir that have ground truth.
+func findSamples(imageDir, truthDir string) ([]sample, error) {
+  var samples []sample
+  err := filepath.WalkDir(imageDir, func(path string, d fs.DirEntry, err error) error {
+    if err != nil {
+      return err
+    }
+    if d.IsDir() || !imageExts[strings.ToLower(filepath.Ext(path))] {
+      return nil
+    }
+
+    rel, _ := filepath.Rel(imageDir, path)
+    base := strings.TrimSuffix(rel, filepath.Ext(rel))
+    truthPath := ""
+    for _, ext := range truthExts {
+      candidate := filepath.Join(truthDir, base+ext)
+      if candidate == path {
+        continue
+      }
+      if _, err := os.Stat(candidate); err == nil {
+        truthPath = candidate
+        break
+      }
+    }
+    if truthPath != "" {
+      samples = append(samples, sample{rel, path, truthPath})
+    }
+    return nil
+  })
+  return samples, err
+}
+
+// load decodes a sample's image and ground truth, logging why if it cannot.
+func load(s sample) (loaded, bool) {
+  img, err := decode(s.path)
+  if err != nil {
+    log.Println(err, "Could not decode image:", s.path)
+    return loaded{}, false
+  }
+  w, h, truth, err := labelio.Load(s.truthPath)
+  if err != nil {
+    log.Println(err, "Could not read ground truth:", s.truthPath)
+    return loaded{}, false
+  }
+  if w != img.Bounds().Dx() || h != img.Bounds().Dy() {
+    log.Println("Ground truth size does not match image:", s.truthPath)
+    return loaded{}, false
+  }
+  return loaded{s.name, img, truth, w, h}, true
+}
+
+func decode(path string) (image.Image, error) {
+  file, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer file.Close()
+  img, _, err := image.Decode(file)
+  return img, err
+}
+
+func run(s loaded, count int, c float64) (result, error) {
+  start := time.Now()
+  size := slic.SuperPixelSizeForCount(s.width, s.height, count)
+  if size < 1 {
+    return result{}, fmt.Errorf("%d superpixels is more than the image has pixels", count)
+  }
+  sl := slic.MakeSlic(s.img, c, size)
+  sl.Run(*iterations)
+  elapsed := time.Since(start)
+
+  report, err := eval.Evaluate(sl.Labels, s.truth, s.width, s.height, s.img, *tolerance)
+  return result{
+    Image:       s.name,
+    Images:      1,
+    Requested:   count,
+    Compactness: c,
+    Iterations:  sl.Iterations,
+    Seconds:     elapsed.Seconds(),
+    Metrics:     report,
+  }, err
+}
+
+func mean(rows []result) result {
+  m := rows[0]
+  m.Image = ""
+  m.Images = len(rows)
+  m.Seconds, m.Metrics = 0, eval.Report{}
+
+  var superpixels, done int
+  mm := &m.Metrics
+  for _, r := range rows {
+    superpixels += r.Metrics.Superpixels
+    done += r.Iterations
+    m.Seconds += r.Seconds
+    mm.BoundaryRecall += r.Metrics.BoundaryRecall
+    mm.UndersegmentationError += r.Metrics.UndersegmentationError
+    mm.AchievableAccuracy += r.Metrics.AchievableAccuracy
+    mm.ExplainedVariation += r.Metrics.ExplainedVariation
+    mm.Compactness += r.Metrics.Compactness
+  }
+  n := float64(len(rows))
+  mm.Superpixels = int(float64(superpixels)/n + 0.5)
+  m.Iterations = int(float64(done)/n + 0.5)
+  m.Seconds /= n
+  mm.BoundaryRecall /= n
+  mm.UndersegmentationError /= n
+  mm.AchievableAccuracy /= n
+  mm.ExplainedVariation /= n
+  mm.Compactness /= n
+  return m
+}
+
+func writeCSV(w io.Writer, results []result) error {
+  cw := csv.NewWriter(w)
+  cw.Write([]string{
+    "image", "images", "requested", "superpixels", "compactness", "iterations", "seconds",
+    "boundary_recall", "undersegmentation_error", "achievable_accuracy",
+    "explained_variation", "compactness_score",
+  })
+  for _, r := range results {
+    cw.Write([]string{
+      r.Image,
+      strconv.Itoa(r.Images),
+      strconv.Itoa(r.Requested),
+      strconv.Itoa(r.Metrics.Superpixels),
+      ftoa(r.Compactness),
+      strconv.Itoa(r.Iterations),
+      ftoa(r.Seconds),
+      ftoa(r.Metrics.BoundaryRecall),
+      ftoa(r.Metrics.UndersegmentationError),
+      ftoa(r.Metrics.AchievableAccuracy),
+      ftoa(r.Metrics.ExplainedVariation),
+      ftoa(r.Metrics.Compactness),
+    })
+  }
+  cw.Flush()
+  return cw.Error()
+}
+
+func parseInts(s string) ([]int, error) {
+  var values []int
+  for _, f := range strings.Split(s, ",") {
+    v, err := strconv.Atoi(strings.TrimSpace(f))
+    if err != nil {
+      return nil, err
+    }
+    if v <= 0 {
+      return nil, fmt.Errorf("%d is not positive", v)
+    }
+    values = append(values, v)
+  }
+  return values, nil
+}
+
+func parseFloats(s string) ([]float64, error) {
+  var values []float64
+  for _, f := range strings.Split(s, ",") {
+    v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+    if err != nil {
+      return nil, err
+    }
+    if !(v > 0) {
+      return nil, fmt.Errorf("%g is not positive", v)
+    }
+    values = append(values, v)
+  }
+  return values, nil
+}
+
+func ftoa(f float64) string {
+  return strconv.FormatFloat(f, 'g', 6, 64)
+}
@@ -1,9 +1,7 @@
 package main
 
 import (
-  "bufio"
   "flag"
-  "fmt"
   "image"
   _ "image/gif"
   _ "image/jpeg"
@@ -14,6 +12,10 @@ import (
   "runtime/pprof"
 
   "github.com/kurige/SLIC"
+  "github.com/kurige/SLIC/colorspace"
+  "github.com/kurige/SLIC/exif"
+  "github.com/kurige/SLIC/labelio"
+  _ "github.com/kurige/SLIC/netpbm"
 )
 
 type handlerFunc func(*os.File)
@@ -24,25 +26,6 @@ func outputPNG(img image.Image, filename string) {
   //jpeg.Encode(fi, img, &jpeg.Options{jpeg.DefaultQuality})
 }
 
-func outputLabels(w, h int, labels []int, filename string) {
-  fi, _ := os.Create(filename)
-  writer := bufio.NewWriter(fi)
-
-  for x := 0; x < w; x++ {
-    for y := 0; y < h; y++ {
-      i := y*w + x
-      label := labels[i]
-      if _, err := fmt.Fprintf(writer, "%d,", label); err != nil {
-        panic(err)
-      }
-    }
-    if _, err := fmt.Fprintf(writer, "\n"); err != nil {
-      panic(err)
-    }
-    writer.Flush()
-  }
-}
-
 var (
   // outputName = flag.String("o", "output", "\t\tName of the output filename (sans extension)")
   // outputExt = flag.Uint("e", 1, "\t\tOutput extension type:\n\t\t\t 1 \t png (default)\n\t\t\t 2 \t jpg")
@@ -53,6 +36,9 @@ var (
   compactness    = flag.Float64("c", 20.0, "Superpixel 'compactness'")
   cpuprofile     = flag.String("cpuprofile", "", "write cpu profile to file")
   iterations     = flag.Int("i", 10, "Number of iterations")
+  labelsOutput   = flag.String("labels", "", "write label map to file (.png, .pgm, .npy, .raw or .seg)")
+  spaceName      = flag.String("space", "lab", "color space to cluster in (lab, luv, oklab, hsv or ycbcr)")
+  orient         = flag.Bool("orient", true, "rotate JPEG input upright using its EXIF orientation")
 )
 
 func main() {
@@ -83,7 +69,12 @@ func main() {
   }
   defer file.Close()
 
-  src_img, _, err := image.Decode(file)
+  var src_img image.Image
+  if *orient {
+    src_img, _, err = exif.Decode(file)
+  } else {
+    src_img, _, err = image.Decode(file)
+  }
   if err != nil {
     log.Println(err, "Could not decode image:", file_name)
     return
@@ -95,9 +86,22 @@ func main() {
     *superpixelsize = slic.SuperPixelSizeForCount(w, h, *superpixels)
   }
 
-  s := slic.MakeSlic(src_img, *compactness, *superpixelsize)
+  var opts slic.Options
+  if space, ok := colorspace.ByName(*spaceName); !ok {
+    log.Println("Unknown color space:", *spaceName)
+    return
+  } else if space != colorspace.LabSpace {
+    // Lab is the default and has a faster conversion path.
+    opts.Space = space
+  }
+
+  s := slic.MakeSlicWithOptions(src_img, *compactness, *superpixelsize, opts)
   s.Run(*iterations)
 
   outputPNG(s.DrawEdgesToImage(src_img), "out.png")
-  // outputLabels(w, h, s.Labels, "out.labels")
+  if *labelsOutput != "" {
+    if err := labelio.Save(*labelsOutput, w, h, s.Labels); err != nil {
+      log.Println(err, "Could not write labels:", *labelsOutput)
+    }
+  }
 }
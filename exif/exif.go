@@ -0,0 +1,208 @@
+// Package exif reads the orientation tag from the EXIF data of JPEG files and
+// applies it, so that images are segmented the way viewers display them.
+// Nothing else in the EXIF data is interpreted.
+package exif
+
+import (
+  "bufio"
+  "bytes"
+  "encoding/binary"
+  "errors"
+  "image"
+  "image/draw"
+  "io"
+)
+
+// Orientation values as defined by EXIF, named for the transform that
+// displays the stored image upright.
+const (
+  Normal     = 1
+  FlipH      = 2
+  Rotate180  = 3
+  FlipV      = 4
+  Transpose  = 5
+  Rotate90   = 6 // clockwise
+  Transverse = 7
+  Rotate270  = 8 // clockwise
+)
+
+var ErrNotJPEG = errors.New("exif: not a JPEG file")
+
+const (
+  markerSOI  = 0xd8
+  markerEOI  = 0xd9
+  markerSOS  = 0xda
+  markerAPP1 = 0xe1
+  tagOrient  = 0x0112
+  typeShort  = 3
+)
+
+// Orientation returns the EXIF orientation of the JPEG read from r, or
+// Normal if it has none or the tag is invalid. It stops reading at the start
+// of the image data.
+func Orientation(r io.Reader) (int, error) {
+  br := bufio.NewReader(r)
+  var soi [2]byte
+  if _, err := io.ReadFull(br, soi[:]); err != nil || soi[0] != 0xff || soi[1] != markerSOI {
+    return 0, ErrNotJPEG
+  }
+  for {
+    marker, err := nextMarker(br)
+    if err != nil {
+      return 0, err
+    }
+    switch {
+    case marker == markerSOS || marker == markerEOI:
+      return Normal, nil
+    case marker >= 0xd0 && marker <= 0xd7 || marker == 0x01:
+      // Standalone markers have no length.
+      continue
+    }
+    var length [2]byte
+    if _, err := io.ReadFull(br, length[:]); err != nil {
+      return 0, err
+    }
+    n := int(binary.BigEndian.Uint16(length[:])) - 2
+    if n < 0 {
+      return 0, ErrNotJPEG
+    }
+    if marker != markerAPP1 {
+      if _, err := br.Discard(n); err != nil {
+        return 0, err
+      }
+      continue
+    }
+    data := make([]byte, n)
+    if _, err := io.ReadFull(br, data); err != nil {
+      return 0, err
+    }
+    if bytes.HasPrefix(data, []byte("Exif\x00\x00")) {
+      return tiffOrientation(data[6:]), nil
+    }
+  }
+}
+
+// nextMarker reads up to and including the next marker code, skipping fill
+// bytes.
+func nextMarker(br *bufio.Reader) (byte, error) {
+  b, err := br.ReadByte()
+  if err != nil {
+    return 0, err
+  }
+  if b != 0xff {
+    return 0, ErrNotJPEG
+  }
+  for b == 0xff {
+    if b, err = br.ReadByte(); err != nil {
+      return 0, err
+    }
+  }
+  return b, nil
+}
+
+// tiffOrientation finds the orientation tag in the first IFD of a TIFF
+// structure, returning Normal if it is missing or malformed.
+func tiffOrientation(tiff []byte) int {
+  if len(tiff) < 8 {
+    return Normal
+  }
+  var order binary.ByteOrder
+  switch string(tiff[:2]) {
+  case "II":
+    order = binary.LittleEndian
+  case "MM":
+    order = binary.BigEndian
+  default:
+    return Normal
+  }
+  if order.Uint16(tiff[2:]) != 42 {
+    return Normal
+  }
+  ifd := int(order.Uint32(tiff[4:]))
+  if ifd < 8 || ifd+2 > len(tiff) {
+    return Normal
+  }
+  count := int(order.Uint16(tiff[ifd:]))
+  for i := 0; i < count; i++ {
+    entry := ifd + 2 + 12*i
+    if entry+12 > len(tiff) {
+      break
+    }
+    if order.Uint16(tiff[entry:]) != tagOrient {
+      continue
+    }
+    if order.Uint16(tiff[entry+2:]) != typeShort || order.Uint32(tiff[entry+4:]) != 1 {
+      return Normal
+    }
+    if v := int(order.Uint16(tiff[entry+8:])); v >= Normal && v <= Rotate270 {
+      return v
+    }
+    return Normal
+  }
+  return Normal
+}
+
+// Apply returns img transformed to display upright for the given
+// orientation. Normal and invalid orientations return img itself; anything
+// else returns a new *image.RGBA with bounds starting at (0, 0).
+func Apply(img image.Image, orientation int) image.Image {
+  if orientation <= Normal || orientation > Rotate270 {
+    return img
+  }
+  b := img.Bounds()
+  src, ok := img.(*image.RGBA)
+  if !ok {
+    src = image.NewRGBA(b)
+    draw.Draw(src, b, img, b.Min, draw.Src)
+  }
+  w, h := b.Dx(), b.Dy()
+  dw, dh := w, h
+  if orientation >= Transpose {
+    dw, dh = h, w
+  }
+  dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+  for y := 0; y < dh; y++ {
+    for x := 0; x < dw; x++ {
+      var sx, sy int
+      switch orientation {
+      case FlipH:
+        sx, sy = w-1-x, y
+      case Rotate180:
+        sx, sy = w-1-x, h-1-y
+      case FlipV:
+        sx, sy = x, h-1-y
+      case Transpose:
+        sx, sy = y, x
+      case Rotate90:
+        sx, sy = y, h-1-x
+      case Transverse:
+        sx, sy = w-1-y, h-1-x
+      case Rotate270:
+        sx, sy = w-1-y, x
+      }
+      s := src.PixOffset(b.Min.X+sx, b.Min.Y+sy)
+      d := dst.PixOffset(x, y)
+      copy(dst.Pix[d:d+4], src.Pix[s:s+4])
+    }
+  }
+  return dst
+}
+
+// Decode decodes an image with image.Decode and, if it is a JPEG, applies its
+// EXIF orientation.
+func Decode(r io.Reader) (image.Image, string, error) {
+  data, err := io.ReadAll(r)
+  if err != nil {
+    return nil, "", err
+  }
+  img, format, err := image.Decode(bytes.NewReader(data))
+  if err != nil || format != "jpeg" {
+    return img, format, err
+  }
+  orientation, err := Orientation(bytes.NewReader(data))
+  if err != nil {
+    // The image decoded, so a damaged header is not worth failing over.
+    return img, format, nil
+  }
+  return Apply(img, orientation), format, nil
+}
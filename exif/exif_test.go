@@ -0,0 +1,144 @@
+package exif
+
+import (
+  "bytes"
+  "encoding/binary"
+  "image"
+  "image/color"
+  "image/jpeg"
+  "testing"
+
+  . "github.com/franela/goblin"
+)
+
+// app1 builds an APP1 segment holding a TIFF structure whose first IFD has a
+// single orientation entry.
+func app1(order binary.ByteOrder, orientation uint16) []byte {
+  var tiff bytes.Buffer
+  if order == binary.LittleEndian {
+    tiff.WriteString("II")
+  } else {
+    tiff.WriteString("MM")
+  }
+  binary.Write(&tiff, order, uint16(42))
+  binary.Write(&tiff, order, uint32(8))
+  binary.Write(&tiff, order, uint16(1))
+  binary.Write(&tiff, order, uint16(tagOrient))
+  binary.Write(&tiff, order, uint16(typeShort))
+  binary.Write(&tiff, order, uint32(1))
+  binary.Write(&tiff, order, orientation)
+  binary.Write(&tiff, order, uint16(0))
+  binary.Write(&tiff, order, uint32(0))
+
+  payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+  seg := []byte{0xff, markerAPP1, 0, 0}
+  binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+  return append(seg, payload...)
+}
+
+// testJPEG encodes a w by h image, dark on the left and light on the right,
+// with an optional APP1 segment spliced in after the SOI marker.
+func testJPEG(w, h int, segment []byte) []byte {
+  img := image.NewGray(image.Rect(0, 0, w, h))
+  for y := 0; y < h; y++ {
+    for x := 0; x < w; x++ {
+      if x >= w/2 {
+        img.SetGray(x, y, color.Gray{255})
+      }
+    }
+  }
+  var buf bytes.Buffer
+  jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95})
+  data := buf.Bytes()
+  out := append([]byte{}, data[:2]...)
+  out = append(out, segment...)
+  return append(out, data[2:]...)
+}
+
+// numbered returns a 3x2 image whose pixels encode their own coordinates.
+func numbered() *image.RGBA {
+  img := image.NewRGBA(image.Rect(10, 20, 13, 22))
+  for y := 0; y < 2; y++ {
+    for x := 0; x < 3; x++ {
+      img.SetRGBA(10+x, 20+y, color.RGBA{uint8(x), uint8(y), 0, 255})
+    }
+  }
+  return img
+}
+
+func TestExif(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("Orientation", func() {
+    g.It("Reads little and big endian tags", func() {
+      for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+        for o := uint16(Normal); o <= Rotate270; o++ {
+          v, err := Orientation(bytes.NewReader(testJPEG(8, 8, app1(order, o))))
+          g.Assert(err == nil).IsTrue()
+          g.Assert(v).Equal(int(o))
+        }
+      }
+    })
+    g.It("Defaults to Normal", func() {
+      v, err := Orientation(bytes.NewReader(testJPEG(8, 8, nil)))
+      g.Assert(err == nil).IsTrue()
+      g.Assert(v).Equal(Normal)
+      v, _ = Orientation(bytes.NewReader(testJPEG(8, 8, app1(binary.BigEndian, 9))))
+      g.Assert(v).Equal(Normal)
+    })
+    g.It("Rejects other formats", func() {
+      _, err := Orientation(bytes.NewReader([]byte("\x89PNG\r\n")))
+      g.Assert(err).Equal(ErrNotJPEG)
+    })
+  })
+
+  g.Describe("Apply", func() {
+    // Where the source pixel (x, y) of numbered ends up, for each orientation.
+    type point struct{ x, y int }
+    cases := map[int]func(x, y int) point{
+      FlipH:      func(x, y int) point { return point{2 - x, y} },
+      Rotate180:  func(x, y int) point { return point{2 - x, 1 - y} },
+      FlipV:      func(x, y int) point { return point{x, 1 - y} },
+      Transpose:  func(x, y int) point { return point{y, x} },
+      Rotate90:   func(x, y int) point { return point{1 - y, x} },
+      Transverse: func(x, y int) point { return point{1 - y, 2 - x} },
+      Rotate270:  func(x, y int) point { return point{y, 2 - x} },
+    }
+    g.It("Moves every pixel where the orientation says", func() {
+      src := numbered()
+      for o, to := range cases {
+        dst := Apply(src, o).(*image.RGBA)
+        for y := 0; y < 2; y++ {
+          for x := 0; x < 3; x++ {
+            p := to(x, y)
+            c := dst.RGBAAt(p.x, p.y)
+            g.Assert([]int{int(c.R), int(c.G)}).Equal([]int{x, y})
+          }
+        }
+      }
+    })
+    g.It("Leaves Normal images alone", func() {
+      src := numbered()
+      g.Assert(Apply(src, Normal) == image.Image(src)).IsTrue()
+      g.Assert(Apply(src, 0) == image.Image(src)).IsTrue()
+    })
+  })
+
+  g.Describe("Decode", func() {
+    g.It("Rotates JPEGs upright", func() {
+      img, format, err := Decode(bytes.NewReader(testJPEG(16, 8, app1(binary.BigEndian, Rotate90))))
+      g.Assert(err == nil).IsTrue()
+      g.Assert(format).Equal("jpeg")
+      g.Assert(img.Bounds().Size()).Equal(image.Pt(8, 16))
+      // The light right half of the stored image is now the bottom half.
+      top, _, _, _ := img.At(4, 2).RGBA()
+      bottom, _, _, _ := img.At(4, 13).RGBA()
+      g.Assert(top < 0x4000 && bottom > 0xc000).IsTrue()
+    })
+    g.It("Keeps JPEGs without EXIF as decoded", func() {
+      img, _, err := Decode(bytes.NewReader(testJPEG(16, 8, nil)))
+      g.Assert(err == nil).IsTrue()
+      _, ok := img.(*image.Gray)
+      g.Assert(ok).IsTrue()
+    })
+  })
+}
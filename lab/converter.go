@@ -0,0 +1,102 @@
+package lab
+
+import (
+  "image"
+)
+
+// Converter converts between an RGB profile and Lab relative to a chosen
+// white point. When the profile's white differs from the Lab white, colors are
+// adapted with Bradford. The zero Converter is not usable; see NewConverter.
+type Converter struct {
+  profile       *Profile
+  white         WhitePoint
+  adapt, revert *matrix
+}
+
+var sRGBWhite = WhitePoint{RefX, RefY, RefZ}
+
+var defaultConverter = NewConverter(sRGBWhite)
+
+// NewConverter returns a converter from sRGB.
+func NewConverter(white WhitePoint) *Converter {
+  return NewProfileConverter(SRGB, white)
+}
+
+func NewProfileConverter(p *Profile, white WhitePoint) *Converter {
+  c := &Converter{profile: p, white: white}
+  if white != p.White {
+    adapt := matrix(Bradford(p.White, white))
+    revert := matrix(Bradford(white, p.White))
+    c.adapt, c.revert = &adapt, &revert
+  }
+  return c
+}
+
+func (c *Converter) White() WhitePoint { return c.white }
+
+func (c *Converter) Profile() *Profile { return c.profile }
+
+func (c *Converter) Rgb2lab(R, G, B uint8) (l, a, b float64) {
+  return c.rgb2lab(float64(R)/255.0, float64(G)/255.0, float64(B)/255.0)
+}
+
+// Rgb2lab16 converts 16-bit channels, as returned by color.Color's RGBA.
+func (c *Converter) Rgb2lab16(R, G, B uint16) (l, a, b float64) {
+  return c.rgb2lab(float64(R)/65535.0, float64(G)/65535.0, float64(B)/65535.0)
+}
+
+func (c *Converter) rgb2lab(R, G, B float64) (l, a, b float64) {
+  p := c.profile
+  x, y, z := p.toXYZ.apply(p.decode(R), p.decode(G), p.decode(B))
+  x *= 100
+  y *= 100
+  z *= 100
+  if c.adapt != nil {
+    x, y, z = c.adapt.apply(x, y, z)
+  }
+  return xyz2labWhite(x, y, z, c.white)
+}
+
+// Lab2rgb converts without any gamut handling: colors outside the profile's
+// gamut wrap around. See Lab2rgbClamped and Lab2rgbMapped.
+func (c *Converter) Lab2rgb(l, a, b float64) (R, G, B uint8) {
+  r, g, bl := c.lab2rgb(l, a, b)
+  R = uint8(r * 255.0)
+  G = uint8(g * 255.0)
+  B = uint8(bl * 255.0)
+  return
+}
+
+// lab2rgb returns encoded channels, nominally in [0, 1].
+func (c *Converter) lab2rgb(l, a, b float64) (R, G, B float64) {
+  x, y, z := lab2xyzWhite(l, a, b, c.white)
+  if c.revert != nil {
+    x, y, z = c.revert.apply(x, y, z)
+  }
+  p := c.profile
+  r, g, bl := p.fromXYZ.apply(x, y, z)
+  return p.encode(r), p.encode(g), p.encode(bl)
+}
+
+func (c *Converter) ImageToLab(img image.Image) Image {
+  b := img.Bounds()
+  canvas := NewImage(image.Rect(0, 0, b.Dx(), b.Dy()))
+  c.convert(img, canvas.SetLAB)
+  return *canvas
+}
+
+func (c *Converter) ImageToLab32(img image.Image) *Image32 {
+  b := img.Bounds()
+  canvas := NewImage32(image.Rect(0, 0, b.Dx(), b.Dy()))
+  c.convert(img, canvas.SetLAB)
+  return canvas
+}
+
+// convert calls set with the Lab color of every pixel of img, relative to its
+// bounds.
+func (c *Converter) convert(img image.Image, set func(x, y int, lc Color)) {
+  lut := c.profile.table()
+  eachRGB(img, func(x, y int, r, g, b uint16) {
+    set(x, y, c.fastRgb2lab(lut, r, g, b))
+  })
+}
@@ -0,0 +1,116 @@
+package lab
+
+import (
+  "bufio"
+  "encoding/binary"
+  "errors"
+  "image"
+  "io"
+  "math"
+)
+
+// Magic starts every image written by Encode. It is followed by the
+// little-endian int32 bounds Min.X, Min.Y, Max.X and Max.Y, a uint32 channel
+// width of 32 or 64 bits, and then the L, a, b channels of every pixel in row
+// order as little-endian IEEE floats.
+const Magic = "SLAB"
+
+var (
+  ErrFormat    = errors.New("lab: not an encoded Lab image")
+  ErrPrecision = errors.New("lab: unsupported channel width")
+  ErrTooLarge  = errors.New("lab: encoded image too large")
+)
+
+// MaxPixels bounds the size of images Decode accepts, so that a corrupt or
+// hostile header cannot make it allocate without limit. At 24 bytes per pixel
+// the default allows 1.5GB for 64-bit images.
+var MaxPixels int64 = 1 << 26
+
+// Encode writes img losslessly. An *Image32 is written with 32-bit channels
+// and anything else with 64-bit channels.
+func Encode(w io.Writer, img Reader) error {
+  bw := bufio.NewWriter(w)
+  b := img.Bounds()
+  bits := uint32(64)
+  p32, is32 := img.(*Image32)
+  if is32 {
+    bits = 32
+  }
+  header := []interface{}{
+    []byte(Magic),
+    [4]int32{int32(b.Min.X), int32(b.Min.Y), int32(b.Max.X), int32(b.Max.Y)},
+    bits,
+  }
+  for _, v := range header {
+    if err := binary.Write(bw, binary.LittleEndian, v); err != nil {
+      return err
+    }
+  }
+
+  var buf [24]byte
+  for y := b.Min.Y; y < b.Max.Y; y++ {
+    for x := b.Min.X; x < b.Max.X; x++ {
+      var n int
+      if is32 {
+        i := p32.PixOffset(x, y)
+        for c := 0; c < 3; c++ {
+          binary.LittleEndian.PutUint32(buf[4*c:], math.Float32bits(p32.Pix[i+c]))
+        }
+        n = 12
+      } else {
+        lc := img.LabAt(x, y)
+        binary.LittleEndian.PutUint64(buf[0:], math.Float64bits(lc.L))
+        binary.LittleEndian.PutUint64(buf[8:], math.Float64bits(lc.A))
+        binary.LittleEndian.PutUint64(buf[16:], math.Float64bits(lc.B))
+        n = 24
+      }
+      if _, err := bw.Write(buf[:n]); err != nil {
+        return err
+      }
+    }
+  }
+  return bw.Flush()
+}
+
+// Decode reads an image written by Encode, returning an *Image or an *Image32
+// to match the channel width it was written with.
+func Decode(r io.Reader) (Reader, error) {
+  br := bufio.NewReader(r)
+  var (
+    magic [4]byte
+    rect  [4]int32
+    bits  uint32
+  )
+  if _, err := io.ReadFull(br, magic[:]); err != nil || string(magic[:]) != Magic {
+    return nil, ErrFormat
+  }
+  if err := binary.Read(br, binary.LittleEndian, &rect); err != nil {
+    return nil, err
+  }
+  if err := binary.Read(br, binary.LittleEndian, &bits); err != nil {
+    return nil, err
+  }
+  if rect[0] > rect[2] || rect[1] > rect[3] {
+    return nil, ErrFormat
+  }
+  if (int64(rect[2])-int64(rect[0]))*(int64(rect[3])-int64(rect[1])) > MaxPixels {
+    return nil, ErrTooLarge
+  }
+  bounds := image.Rect(int(rect[0]), int(rect[1]), int(rect[2]), int(rect[3]))
+
+  switch bits {
+  case 64:
+    img := NewImage(bounds)
+    if err := binary.Read(br, binary.LittleEndian, img.Pix); err != nil {
+      return nil, err
+    }
+    return img, nil
+  case 32:
+    img := NewImage32(bounds)
+    if err := binary.Read(br, binary.LittleEndian, img.Pix); err != nil {
+      return nil, err
+    }
+    return img, nil
+  }
+  return nil, ErrPrecision
+}
@@ -0,0 +1,87 @@
+package lab
+
+import (
+  "bytes"
+  "encoding/binary"
+  "image"
+  "testing"
+
+  . "github.com/franela/goblin"
+)
+
+func TestEncode(t *testing.T) {
+  _g := Goblin(t)
+  _g.Describe("Bulk RGBA conversion", func() {
+    _g.It("Round trips an sRGB image", func() {
+      src := testRGBA()
+      img := ImageToLab(src)
+      _g.Assert(ToRGBA(&img).Pix).Equal(src.Pix)
+      _g.Assert(ToNRGBA(ImageToLab32(src)).Pix).Equal(src.Pix)
+    })
+    _g.It("Keeps the bounds of a sub-image", func() {
+      src := testRGBA()
+      img := ImageToLab(src)
+      r := image.Rect(5, 4, 30, 20)
+      out := ToRGBA(img.SubImage(r).(*Image))
+      _g.Assert(out.Bounds()).Equal(r)
+      for y := r.Min.Y; y < r.Max.Y; y++ {
+        for x := r.Min.X; x < r.Max.X; x++ {
+          _g.Assert(out.RGBAAt(x, y)).Equal(src.RGBAAt(x+3, y+5))
+        }
+      }
+    })
+    _g.It("Converts other readers the same way", func() {
+      img := ImageToLab(testRGBA())
+      _g.Assert(ToRGBA(labReader{&img}).Pix).Equal(ToRGBA(&img).Pix)
+    })
+  })
+  _g.Describe("Encoding", func() {
+    _g.It("Round trips 64-bit images exactly", func() {
+      img := ImageToLab(testRGBA())
+      sub := img.SubImage(image.Rect(3, 2, 20, 21)).(*Image)
+      var buf bytes.Buffer
+      _g.Assert(Encode(&buf, sub)).Equal(nil)
+      dec, err := Decode(&buf)
+      _g.Assert(err).Equal(nil)
+      out := dec.(*Image)
+      _g.Assert(out.Bounds()).Equal(sub.Bounds())
+      for y := 2; y < 21; y++ {
+        for x := 3; x < 20; x++ {
+          _g.Assert(out.LabAt(x, y)).Equal(sub.LabAt(x, y))
+        }
+      }
+    })
+    _g.It("Round trips 32-bit images exactly", func() {
+      img := ImageToLab32(testRGBA())
+      var buf bytes.Buffer
+      _g.Assert(Encode(&buf, img)).Equal(nil)
+      _g.Assert(buf.Len()).Equal(len(Magic) + 20 + 37*25*12)
+      dec, err := Decode(&buf)
+      _g.Assert(err).Equal(nil)
+      _g.Assert(dec.(*Image32).Pix).Equal(img.Pix)
+    })
+    _g.It("Rejects other data", func() {
+      _, err := Decode(bytes.NewReader([]byte("\x89PNG\r\n\x1a\n")))
+      _g.Assert(err).Equal(ErrFormat)
+      var buf bytes.Buffer
+      Encode(&buf, NewImage(image.Rect(0, 0, 1, 1)))
+      data := buf.Bytes()
+      data[20] = 16
+      _, err = Decode(bytes.NewReader(data))
+      _g.Assert(err).Equal(ErrPrecision)
+    })
+    _g.It("Rejects oversized headers before allocating", func() {
+      header := []byte(Magic)
+      for _, v := range []uint32{0, 0, 2000000000, 2000000000, 64} {
+        header = binary.LittleEndian.AppendUint32(header, v)
+      }
+      _, err := Decode(bytes.NewReader(header))
+      _g.Assert(err).Equal(ErrTooLarge)
+    })
+  })
+}
+
+// labReader hides the concrete image type from the fast paths.
+type labReader struct {
+  Reader
+}
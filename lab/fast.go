@@ -0,0 +1,114 @@
+package lab
+
+import (
+  "image"
+  "image/color"
+  "math"
+)
+
+// fastRgb2lab is c.Rgb2lab16 using the profile's linearization table and
+// math.Cbrt.
+func (c *Converter) fastRgb2lab(lut []float64, r, g, b uint16) Color {
+  const epsilon float64 = 0.008856
+  const kappa float64 = 7.787
+
+  X, Y, Z := c.profile.toXYZ.apply(lut[r], lut[g], lut[b])
+  X *= 100
+  Y *= 100
+  Z *= 100
+  if c.adapt != nil {
+    X, Y, Z = c.adapt.apply(X, Y, Z)
+  }
+  X /= c.white.X
+  Y /= c.white.Y
+  Z /= c.white.Z
+
+  if X > epsilon {
+    X = math.Cbrt(X)
+  } else {
+    X = (kappa * X) + (16.0 / 116.0)
+  }
+  if Y > epsilon {
+    Y = math.Cbrt(Y)
+  } else {
+    Y = (kappa * Y) + (16.0 / 116.0)
+  }
+  if Z > epsilon {
+    Z = math.Cbrt(Z)
+  } else {
+    Z = (kappa * Z) + (16.0 / 116.0)
+  }
+
+  return Color{(116.0 * Y) - 16.0, 500.0 * (X - Y), 200.0 * (Y - Z)}
+}
+
+// eachRGB calls fn with the 16-bit color of every pixel of img, relative to
+// its bounds, as returned by the pixel's RGBA method. Common image types are
+// read directly rather than through color.Color.
+func eachRGB(img image.Image, fn func(x, y int, r, g, b uint16)) {
+  b := img.Bounds()
+  switch img := img.(type) {
+  case *image.RGBA:
+    for y := b.Min.Y; y < b.Max.Y; y++ {
+      i := img.PixOffset(b.Min.X, y)
+      for x := b.Min.X; x < b.Max.X; x++ {
+        p := img.Pix[i : i+4 : i+4]
+        fn(x-b.Min.X, y-b.Min.Y, uint16(p[0])*0x101, uint16(p[1])*0x101, uint16(p[2])*0x101)
+        i += 4
+      }
+    }
+  case *image.NRGBA:
+    for y := b.Min.Y; y < b.Max.Y; y++ {
+      i := img.PixOffset(b.Min.X, y)
+      for x := b.Min.X; x < b.Max.X; x++ {
+        p := img.Pix[i : i+4 : i+4]
+        if p[3] == 0xff {
+          fn(x-b.Min.X, y-b.Min.Y, uint16(p[0])*0x101, uint16(p[1])*0x101, uint16(p[2])*0x101)
+        } else {
+          r, g, bl, _ := color.NRGBA{p[0], p[1], p[2], p[3]}.RGBA()
+          fn(x-b.Min.X, y-b.Min.Y, uint16(r), uint16(g), uint16(bl))
+        }
+        i += 4
+      }
+    }
+  case *image.YCbCr:
+    for y := b.Min.Y; y < b.Max.Y; y++ {
+      for x := b.Min.X; x < b.Max.X; x++ {
+        yi := img.YOffset(x, y)
+        ci := img.COffset(x, y)
+        r, g, bl, _ := color.YCbCr{img.Y[yi], img.Cb[ci], img.Cr[ci]}.RGBA()
+        fn(x-b.Min.X, y-b.Min.Y, uint16(r), uint16(g), uint16(bl))
+      }
+    }
+  case *image.Gray:
+    for y := b.Min.Y; y < b.Max.Y; y++ {
+      i := img.PixOffset(b.Min.X, y)
+      for x := b.Min.X; x < b.Max.X; x++ {
+        v := uint16(img.Pix[i]) * 0x101
+        fn(x-b.Min.X, y-b.Min.Y, v, v, v)
+        i++
+      }
+    }
+  case *image.RGBA64:
+    for y := b.Min.Y; y < b.Max.Y; y++ {
+      for x := b.Min.X; x < b.Max.X; x++ {
+        c := img.RGBA64At(x, y)
+        fn(x-b.Min.X, y-b.Min.Y, c.R, c.G, c.B)
+      }
+    }
+  case *image.Gray16:
+    for y := b.Min.Y; y < b.Max.Y; y++ {
+      for x := b.Min.X; x < b.Max.X; x++ {
+        v := img.Gray16At(x, y).Y
+        fn(x-b.Min.X, y-b.Min.Y, v, v, v)
+      }
+    }
+  default:
+    for y := b.Min.Y; y < b.Max.Y; y++ {
+      for x := b.Min.X; x < b.Max.X; x++ {
+        r, g, bl, _ := img.At(x, y).RGBA()
+        fn(x-b.Min.X, y-b.Min.Y, uint16(r), uint16(g), uint16(bl))
+      }
+    }
+  }
+}
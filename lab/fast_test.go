@@ -0,0 +1,103 @@
+package lab
+
+import (
+  "image"
+  "image/color"
+  "image/draw"
+  "math"
+  "testing"
+
+  . "github.com/franela/goblin"
+)
+
+const (
+  TOLERANCE   = 1e-9
+  TOLERANCE32 = 1e-4
+)
+
+func near(c1, c2 Color, tolerance float64) bool {
+  return math.Abs(c1.L-c2.L) < tolerance &&
+    math.Abs(c1.A-c2.A) < tolerance &&
+    math.Abs(c1.B-c2.B) < tolerance
+}
+
+// opaque hides an image's concrete type, forcing the draw.Draw path.
+type opaque struct {
+  image.Image
+}
+
+func slowImageToLab(img image.Image) *Image {
+  b := img.Bounds()
+  canvas := NewImage(image.Rect(0, 0, b.Dx(), b.Dy()))
+  draw.Draw(canvas, canvas.Bounds(), opaque{img}, b.Min, draw.Src)
+  return canvas
+}
+
+func sameImage(img1, img2 Reader, tolerance float64) bool {
+  b := img1.Bounds()
+  for y := b.Min.Y; y < b.Max.Y; y++ {
+    for x := b.Min.X; x < b.Max.X; x++ {
+      if !near(img1.LabAt(x, y), img2.LabAt(x, y), tolerance) {
+        return false
+      }
+    }
+  }
+  return true
+}
+
+func testRGBA() *image.RGBA {
+  img := image.NewRGBA(image.Rect(3, 5, 40, 30))
+  for y := 5; y < 30; y++ {
+    for x := 3; x < 40; x++ {
+      img.Set(x, y, color.RGBA{uint8(x * 6), uint8(y * 8), uint8(x * y), 255})
+    }
+  }
+  return img
+}
+
+func TestFastConversion(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("Lookup table conversion", func() {
+    g.It("Matches Rgb2lab", func() {
+      lut := SRGB.table()
+      for r := 0; r < 256; r += 3 {
+        for gr := 0; gr < 256; gr += 3 {
+          for b := 0; b < 256; b += 3 {
+            l, a, bb := Rgb2lab(uint8(r), uint8(gr), uint8(b))
+            if !near(defaultConverter.fastRgb2lab(lut, uint16(r)*0x101, uint16(gr)*0x101, uint16(b)*0x101), Color{l, a, bb}, TOLERANCE) {
+              g.Fail(color.RGBA{uint8(r), uint8(gr), uint8(b), 255})
+            }
+          }
+        }
+      }
+    })
+    g.It("Converts RGBA", func() {
+      img := testRGBA()
+      fast := ImageToLab(img)
+      g.Assert(sameImage(&fast, slowImageToLab(img), TOLERANCE)).IsTrue()
+    })
+    g.It("Converts translucent NRGBA", func() {
+      img := image.NewNRGBA(image.Rect(0, 0, 40, 30))
+      for y := 0; y < 30; y++ {
+        for x := 0; x < 40; x++ {
+          img.Set(x, y, color.NRGBA{uint8(x * 6), uint8(y * 8), uint8(x * y), uint8(255 - x)})
+        }
+      }
+      fast := ImageToLab(img)
+      g.Assert(sameImage(&fast, slowImageToLab(img), TOLERANCE)).IsTrue()
+    })
+    g.It("Converts subsampled YCbCr", func() {
+      img := image.NewYCbCr(image.Rect(1, 1, 41, 31), image.YCbCrSubsampleRatio420)
+      for i := range img.Y {
+        img.Y[i] = uint8(i * 7)
+      }
+      for i := range img.Cb {
+        img.Cb[i] = uint8(i * 3)
+        img.Cr[i] = uint8(255 - i*5)
+      }
+      fast := ImageToLab(img)
+      g.Assert(sameImage(&fast, slowImageToLab(img), TOLERANCE)).IsTrue()
+      g.Assert(sameImage(ImageToLab32(img), slowImageToLab(img), TOLERANCE32)).IsTrue()
+    })
+  })
+}
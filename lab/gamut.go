@@ -0,0 +1,75 @@
+package lab
+
+import (
+  "math"
+)
+
+// gamutTolerance is how far outside [0, 1] an encoded channel may fall and
+// still round to a valid 8-bit value.
+const gamutTolerance = 0.5 / 255.0
+
+// InGamut reports whether the Lab color can be represented in the
+// converter's RGB profile.
+func (c *Converter) InGamut(l, a, b float64) bool {
+  R, G, B := c.lab2rgb(l, a, b)
+  return inUnit(R) && inUnit(G) && inUnit(B)
+}
+
+// Lab2rgbClamped converts like Lab2rgb, but clamps every channel to the
+// profile's range instead of letting it wrap, and rounds to nearest.
+func (c *Converter) Lab2rgbClamped(l, a, b float64) (R, G, B uint8) {
+  r, g, bl := c.lab2rgb(l, a, b)
+  return clamp8(r), clamp8(g), clamp8(bl)
+}
+
+// Lab2rgbMapped brings out of gamut colors into gamut by reducing their
+// chroma while keeping lightness and hue, which preserves their appearance
+// better than clamping channels independently. Lightness outside [0, 100] is
+// clamped first.
+func (c *Converter) Lab2rgbMapped(l, a, b float64) (R, G, B uint8) {
+  l = math.Max(0, math.Min(100, l))
+  if c.InGamut(l, a, b) {
+    return c.Lab2rgbClamped(l, a, b)
+  }
+
+  // Neutral grays are always in gamut, so bisect on the chroma scale.
+  lo, hi := 0.0, 1.0
+  for i := 0; i < 24; i++ {
+    mid := (lo + hi) / 2
+    if c.InGamut(l, a*mid, b*mid) {
+      lo = mid
+    } else {
+      hi = mid
+    }
+  }
+  return c.Lab2rgbClamped(l, a*lo, b*lo)
+}
+
+// InGamut reports whether the Lab color is within the sRGB gamut.
+func InGamut(l, a, b float64) bool {
+  return defaultConverter.InGamut(l, a, b)
+}
+
+// Lab2rgbClamped and Lab2rgbMapped are the sRGB versions of the Converter
+// methods.
+func Lab2rgbClamped(l, a, b float64) (R, G, B uint8) {
+  return defaultConverter.Lab2rgbClamped(l, a, b)
+}
+
+func Lab2rgbMapped(l, a, b float64) (R, G, B uint8) {
+  return defaultConverter.Lab2rgbMapped(l, a, b)
+}
+
+func inUnit(v float64) bool {
+  return v >= -gamutTolerance && v <= 1+gamutTolerance
+}
+
+func clamp8(v float64) uint8 {
+  if v <= 0 || math.IsNaN(v) {
+    return 0
+  }
+  if v >= 1 {
+    return 255
+  }
+  return uint8(v*255.0 + 0.5)
+}
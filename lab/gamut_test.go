@@ -0,0 +1,67 @@
+package lab
+
+import (
+  "math"
+  "testing"
+
+  . "github.com/franela/goblin"
+)
+
+// A saturated red well outside sRGB, which Lab2rgb wraps around.
+const OUT_L_, OUT_A_, OUT_B_ float64 = 50, 120, 20
+
+func hue(a, b float64) float64 {
+  return math.Atan2(b, a) * 180 / math.Pi
+}
+
+func TestGamut(t *testing.T) {
+  _g := Goblin(t)
+  _g.Describe("Gamut", func() {
+    _g.It("Reports sRGB colors as in gamut", func() {
+      _g.Assert(InGamut(BLACK_L_, BLACK_A_, BLACK_B_)).IsTrue()
+      _g.Assert(InGamut(WHITE_L_, WHITE_A_, WHITE_B_)).IsTrue()
+      _g.Assert(InGamut(SEMI_RED_L_, SEMI_RED_A_, SEMI_RED_B_)).IsTrue()
+      _g.Assert(InGamut(SEMI_GREEN_L_, SEMI_GREEN_A_, SEMI_GREEN_B_)).IsTrue()
+      _g.Assert(InGamut(SEMI_BLUE_L_, SEMI_BLUE_A_, SEMI_BLUE_B_)).IsTrue()
+    })
+    _g.It("Reports out of gamut colors", func() {
+      _g.Assert(InGamut(OUT_L_, OUT_A_, OUT_B_)).IsFalse()
+      _g.Assert(InGamut(110, 0, 0)).IsFalse()
+      _g.Assert(NewProfileConverter(DisplayP3, sRGBWhite).InGamut(OUT_L_, OUT_A_/2, OUT_B_)).IsTrue()
+    })
+  })
+  _g.Describe("Clamped conversion", func() {
+    _g.It("Rounds in gamut colors", func() {
+      r, g, b := Lab2rgbClamped(SEMI_RED_L_, SEMI_RED_A_, SEMI_RED_B_)
+      _g.Assert(r).Equal(SEMI_RED_R)
+      _g.Assert(g).Equal(SEMI_RED_G)
+      _g.Assert(b).Equal(SEMI_RED_B)
+    })
+    _g.It("Clamps instead of wrapping", func() {
+      r, g, b := Lab2rgbClamped(OUT_L_, OUT_A_, OUT_B_)
+      _g.Assert(r).Equal(uint8(255))
+      _g.Assert(g).Equal(uint8(0))
+      _g.Assert(b < 128).IsTrue()
+
+      r, g, b = Lab2rgbClamped(110, 0, 0)
+      _g.Assert([]uint8{r, g, b}).Equal([]uint8{255, 255, 255})
+      r, g, b = Lab2rgbClamped(-10, 0, 0)
+      _g.Assert([]uint8{r, g, b}).Equal([]uint8{0, 0, 0})
+    })
+  })
+  _g.Describe("Mapped conversion", func() {
+    _g.It("Leaves in gamut colors alone", func() {
+      r, g, b := Lab2rgbMapped(SEMI_BLUE_L_, SEMI_BLUE_A_, SEMI_BLUE_B_)
+      _g.Assert(r).Equal(SEMI_BLUE_R)
+      _g.Assert(g).Equal(SEMI_BLUE_G)
+      _g.Assert(b).Equal(SEMI_BLUE_B)
+    })
+    _g.It("Keeps lightness and hue", func() {
+      r, g, b := Lab2rgbMapped(OUT_L_, OUT_A_, OUT_B_)
+      l, a, bb := Rgb2lab(r, g, b)
+      _g.Assert(math.Abs(l-OUT_L_) < 1.5).IsTrue()
+      _g.Assert(math.Abs(hue(a, bb)-hue(OUT_A_, OUT_B_)) < 3).IsTrue()
+      _g.Assert(chroma(a, bb) < chroma(OUT_A_, OUT_B_)).IsTrue()
+    })
+  })
+}
@@ -0,0 +1,85 @@
+package lab
+
+import (
+  "image"
+  "image/color"
+)
+
+// Reader is implemented by images that store Lab colors and can return them
+// without boxing them in a color.Color.
+type Reader interface {
+  image.Image
+  LabAt(x, y int) Color
+}
+
+// Image32 is an Image with float32 channels. It takes half the memory of
+// Image at a precision that is still well below a just noticeable difference.
+type Image32 struct {
+  Pix    []float32
+  Stride int
+  Rect   image.Rectangle
+}
+
+func NewImage32(r image.Rectangle) *Image32 {
+  w, h := r.Dx(), r.Dy()
+  buf := make([]float32, 3*w*h)
+  return &Image32{buf, 3 * w, r}
+}
+
+func (p *Image32) ColorModel() color.Model { return ColorModel }
+
+func (p *Image32) Bounds() image.Rectangle { return p.Rect }
+
+func (p *Image32) At(x, y int) color.Color {
+  return p.LabAt(x, y)
+}
+
+func (p *Image32) LabAt(x, y int) Color {
+  if !(image.Point{x, y}.In(p.Rect)) {
+    return Color{}
+  }
+  i := p.PixOffset(x, y)
+  return Color{float64(p.Pix[i+0]), float64(p.Pix[i+1]), float64(p.Pix[i+2])}
+}
+
+func (p *Image32) PixOffset(x, y int) int {
+  return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*3
+}
+
+func (p *Image32) Set(x, y int, c color.Color) {
+  if !(image.Point{x, y}.In(p.Rect)) {
+    return
+  }
+  i := p.PixOffset(x, y)
+  c1 := ColorModel.Convert(c).(Color)
+  p.Pix[i+0] = float32(c1.L)
+  p.Pix[i+1] = float32(c1.A)
+  p.Pix[i+2] = float32(c1.B)
+}
+
+func (p *Image32) SetLAB(x, y int, c Color) {
+  if !(image.Point{x, y}.In(p.Rect)) {
+    return
+  }
+  i := p.PixOffset(x, y)
+  p.Pix[i+0] = float32(c.L)
+  p.Pix[i+1] = float32(c.A)
+  p.Pix[i+2] = float32(c.B)
+}
+
+func (p *Image32) SubImage(r image.Rectangle) image.Image {
+  r = r.Intersect(p.Rect)
+  if r.Empty() {
+    return &Image32{}
+  }
+  i := p.PixOffset(r.Min.X, r.Min.Y)
+  return &Image32{
+    Pix:    p.Pix[i:],
+    Stride: p.Stride,
+    Rect:   r,
+  }
+}
+
+func ImageToLab32(img image.Image) *Image32 {
+  return defaultConverter.ImageToLab32(img)
+}
@@ -3,7 +3,6 @@ package lab
 import (
   "image"
   "image/color"
-  "image/draw"
   "math"
 )
 
@@ -76,13 +75,17 @@ func xyz2rgb(x, y, z float64) (r, g, b uint8) {
 }
 
 func xyz2lab(x, y, z float64) (L, A, B float64) {
+  return xyz2labWhite(x, y, z, WhitePoint{RefX, RefY, RefZ})
+}
+
+func xyz2labWhite(x, y, z float64, white WhitePoint) (L, A, B float64) {
   const epsilon float64 = 0.008856
   const kappa float64 = 7.787
 
   var (
-    X = x / RefX
-    Y = y / RefY
-    Z = z / RefZ
+    X = x / white.X
+    Y = y / white.Y
+    Z = z / white.Z
   )
 
   if X > epsilon {
@@ -108,6 +111,10 @@ func xyz2lab(x, y, z float64) (L, A, B float64) {
 }
 
 func lab2xyz(l, a, b float64) (x, y, z float64) {
+  return lab2xyzWhite(l, a, b, WhitePoint{RefX, RefY, RefZ})
+}
+
+func lab2xyzWhite(l, a, b float64, white WhitePoint) (x, y, z float64) {
   y = (l + 16.0) / 116.0
   x = a/500.0 + y
   z = y - b/200.0
@@ -131,9 +138,9 @@ func lab2xyz(l, a, b float64) (x, y, z float64) {
     z = (z - 16.0/116.0) / 7.787
   }
 
-  x = RefX * (x / 100)
-  y = RefY * (y / 100)
-  z = RefZ * (z / 100)
+  x = white.X * (x / 100)
+  y = white.Y * (y / 100)
+  z = white.Z * (z / 100)
   return
 }
 
@@ -142,6 +149,12 @@ func Rgb2lab(R, G, B uint8) (l, a, b float64) {
   return xyz2lab(x, y, z)
 }
 
+// Rgb2lab16 is Rgb2lab for 16-bit channels, as returned by color.Color's
+// RGBA.
+func Rgb2lab16(R, G, B uint16) (l, a, b float64) {
+  return defaultConverter.Rgb2lab16(R, G, B)
+}
+
 func Lab2rgb(l, a, b float64) (R, G, B uint8) {
   x, y, z := lab2xyz(l, a, b)
   return xyz2rgb(x, y, z)
@@ -170,7 +183,7 @@ func labModel(c color.Color) color.Color {
   }
   var (
     r, g, b, _ = c.RGBA()
-    L, A, B    = Rgb2lab(uint8(r>>8), uint8(g>>8), uint8(b>>8))
+    L, A, B    = Rgb2lab16(uint16(r), uint16(g), uint16(b))
   )
   return Color{L, A, B}
 }
@@ -193,6 +206,10 @@ func (p *Image) ColorModel() color.Model { return ColorModel }
 func (p *Image) Bounds() image.Rectangle { return p.Rect }
 
 func (p *Image) At(x, y int) color.Color {
+  return p.LabAt(x, y)
+}
+
+func (p *Image) LabAt(x, y int) Color {
   if !(image.Point{x, y}.In(p.Rect)) {
     return Color{}
   }
@@ -245,9 +262,9 @@ func (p *Image) SubImage(r image.Rectangle) image.Image {
   }
 }
 
+// ImageToLab converts img from sRGB to Lab relative to D65, at the full 16-bit
+// precision of color.Color. Channels are linearized with a lookup table, and
+// common image types are read without going through color.Color.
 func ImageToLab(img image.Image) Image {
-  b := img.Bounds()
-  canvas := NewImage(image.Rect(0, 0, b.Dx(), b.Dy()))
-  draw.Draw(canvas, canvas.Bounds(), img, b.Min, draw.Src)
-  return *canvas
+  return defaultConverter.ImageToLab(img)
 }
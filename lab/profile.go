@@ -0,0 +1,111 @@
+package lab
+
+import (
+  "math"
+  "sync"
+)
+
+// Profile describes an RGB color space: its primaries, as the matrix from
+// linear RGB to XYZ, its white point and its transfer function.
+type Profile struct {
+  Name  string
+  White WhitePoint
+
+  toXYZ, fromXYZ matrix
+  decode, encode func(float64) float64
+
+  once sync.Once
+  lut  []float64
+}
+
+// NewProfile returns a profile with the given RGB to XYZ matrix, for channels
+// and XYZ in [0, 1]. Decode maps an encoded channel value to linear light and
+// encode is its inverse.
+func NewProfile(name string, white WhitePoint, toXYZ [3][3]float64, decode, encode func(float64) float64) *Profile {
+  m := matrix(toXYZ)
+  return &Profile{
+    Name:    name,
+    White:   white,
+    toXYZ:   m,
+    fromXYZ: *m.inverse(),
+    decode:  decode,
+    encode:  encode,
+  }
+}
+
+var (
+  // SRGB is the default profile. Its inverse matrix is the rounded one
+  // Lab2rgb has always used rather than the exact inverse.
+  SRGB = &Profile{
+    Name:  "sRGB",
+    White: sRGBWhite,
+    toXYZ: matrix{
+      {0.4124, 0.3576, 0.1805},
+      {0.2126, 0.7152, 0.0722},
+      {0.0193, 0.1192, 0.9505},
+    },
+    fromXYZ: matrix{
+      {3.2406, -1.5372, -0.4986},
+      {-0.9689, 1.8758, 0.0415},
+      {0.0557, -0.2040, 1.0570},
+    },
+    decode: srgbDecode,
+    encode: srgbEncode,
+  }
+  LinearSRGB = NewProfile("linear sRGB", sRGBWhite, SRGB.toXYZ, identity, identity)
+  DisplayP3  = NewProfile("Display P3", sRGBWhite, [3][3]float64{
+    {0.4865709, 0.2656677, 0.1982173},
+    {0.2289746, 0.6917385, 0.0792869},
+    {0.0000000, 0.0451134, 1.0439444},
+  }, srgbDecode, srgbEncode)
+  AdobeRGB = NewProfile("Adobe RGB (1998)", sRGBWhite, [3][3]float64{
+    {0.5767309, 0.1855540, 0.1881852},
+    {0.2973769, 0.6273491, 0.0752741},
+    {0.0270343, 0.0706872, 0.9911085},
+  }, gammaDecode(563.0/256.0), gammaEncode(563.0/256.0))
+)
+
+// table returns the linear value of every 16-bit channel value.
+func (p *Profile) table() []float64 {
+  p.once.Do(func() {
+    p.lut = make([]float64, 1<<16)
+    for i := range p.lut {
+      p.lut[i] = p.decode(float64(i) / 65535.0)
+    }
+  })
+  return p.lut
+}
+
+func srgbDecode(v float64) float64 {
+  if v > 0.04045 {
+    return math.Pow(((v + 0.055) / 1.055), 2.4)
+  }
+  return v / 12.92
+}
+
+func srgbEncode(v float64) float64 {
+  if v > 0.0031308 {
+    return 1.055*math.Pow(v, (1/2.4)) - 0.055
+  }
+  return 12.92 * v
+}
+
+func gammaDecode(gamma float64) func(float64) float64 {
+  return func(v float64) float64 {
+    if v <= 0 {
+      return 0
+    }
+    return math.Pow(v, gamma)
+  }
+}
+
+func gammaEncode(gamma float64) func(float64) float64 {
+  return func(v float64) float64 {
+    if v <= 0 {
+      return 0
+    }
+    return math.Pow(v, 1/gamma)
+  }
+}
+
+func identity(v float64) float64 { return v }
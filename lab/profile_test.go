@@ -0,0 +1,95 @@
+package lab
+
+import (
+  "image/color"
+  "math"
+  "testing"
+
+  . "github.com/franela/goblin"
+)
+
+func chroma(a, b float64) float64 {
+  return math.Sqrt(a*a + b*b)
+}
+
+func TestSixteenBit(t *testing.T) {
+  _g := Goblin(t)
+  _g.Describe("16-bit input", func() {
+    _g.It("Matches 8-bit conversion at 8-bit values", func() {
+      for v := 0; v < 256; v += 5 {
+        l8, a8, b8 := Rgb2lab(uint8(v), SEMI_RED_G, SEMI_RED_B)
+        l16, a16, b16 := Rgb2lab16(uint16(v)*0x101, uint16(SEMI_RED_G)*0x101, uint16(SEMI_RED_B)*0x101)
+        _g.Assert(near(Color{l8, a8, b8}, Color{l16, a16, b16}, TOLERANCE)).IsTrue()
+      }
+    })
+    _g.It("Resolves values between 8-bit steps", func() {
+      l1, _, _ := Rgb2lab16(0x8080, 0x8080, 0x8080)
+      l2, _, _ := Rgb2lab16(0x80c0, 0x80c0, 0x80c0)
+      l3, _, _ := Rgb2lab16(0x8181, 0x8181, 0x8181)
+      _g.Assert(l1 < l2 && l2 < l3).IsTrue()
+    })
+    _g.It("Keeps 16-bit precision through ColorModel", func() {
+      c1 := ColorModel.Convert(Color{}).(Color)
+      _g.Assert(c1).Equal(Color{})
+      l, _, _ := Rgb2lab16(0x80c0, 0x80c0, 0x80c0)
+      c2 := ColorModel.Convert(color.Gray16{0x80c0}).(Color)
+      _g.Assert(c2.L).Equal(l)
+    })
+  })
+}
+
+func TestProfiles(t *testing.T) {
+  _g := Goblin(t)
+  _g.Describe("Profiles", func() {
+    white := D65.WhitePoint(Observer2)
+    profiles := []*Profile{SRGB, LinearSRGB, DisplayP3, AdobeRGB}
+
+    _g.It("Map white to neutral", func() {
+      for _, p := range profiles {
+        l, a, b := NewProfileConverter(p, white).Rgb2lab(255, 255, 255)
+        _g.Assert(math.Abs(l-100) < 0.05).IsTrue()
+        _g.Assert(math.Abs(a) < 0.05).IsTrue()
+        _g.Assert(math.Abs(b) < 0.05).IsTrue()
+      }
+    })
+    _g.It("Round trip", func() {
+      for _, p := range profiles {
+        c := NewProfileConverter(p, white)
+        r, g, b := c.Lab2rgb(c.Rgb2lab(SEMI_BLUE_R, SEMI_BLUE_G+40, SEMI_BLUE_B))
+        _g.Assert(math.Abs(float64(r)-float64(SEMI_BLUE_R)) <= 1).IsTrue()
+        _g.Assert(math.Abs(float64(g)-float64(SEMI_BLUE_G+40)) <= 1).IsTrue()
+        _g.Assert(math.Abs(float64(b)-float64(SEMI_BLUE_B)) <= 1).IsTrue()
+      }
+    })
+    _g.It("Have wider gamuts than sRGB", func() {
+      _, sa, sb := Rgb2lab(255, 0, 0)
+      _, pa, pb := NewProfileConverter(DisplayP3, white).Rgb2lab(255, 0, 0)
+      _g.Assert(chroma(pa, pb) > chroma(sa, sb)).IsTrue()
+
+      _, sa, sb = Rgb2lab(0, 255, 0)
+      _, aa, ab := NewProfileConverter(AdobeRGB, white).Rgb2lab(0, 255, 0)
+      _g.Assert(chroma(aa, ab) > chroma(sa, sb)).IsTrue()
+    })
+    _g.It("Treat linear values as linear", func() {
+      c := NewProfileConverter(LinearSRGB, white)
+      lin := uint16(srgbDecode(float64(SEMI_GREEN_G)/255.0)*65535 + 0.5)
+      l1, a1, b1 := c.Rgb2lab16(0, lin, 0)
+      l2, a2, b2 := Rgb2lab(0, SEMI_GREEN_G, 0)
+      _g.Assert(near(Color{l1, a1, b1}, Color{l2, a2, b2}, 0.01)).IsTrue()
+    })
+    _g.It("Invert their matrices", func() {
+      for _, p := range profiles[1:] {
+        m := p.toXYZ.mul(&p.fromXYZ)
+        for i := 0; i < 3; i++ {
+          for j := 0; j < 3; j++ {
+            want := 0.0
+            if i == j {
+              want = 1
+            }
+            _g.Assert(math.Abs(m[i][j]-want) < 1e-9).IsTrue()
+          }
+        }
+      }
+    })
+  })
+}
@@ -0,0 +1,63 @@
+package lab
+
+import "image"
+
+// ToRGBA converts img to sRGB in one pass, clamping colors outside the gamut.
+// The result has the same bounds as img.
+func ToRGBA(img Reader) *image.RGBA {
+  return defaultConverter.ToRGBA(img)
+}
+
+// ToNRGBA is ToRGBA for callers that want an *image.NRGBA. Lab colors are
+// opaque, so the pixels are the same.
+func ToNRGBA(img Reader) *image.NRGBA {
+  return defaultConverter.ToNRGBA(img)
+}
+
+func (c *Converter) ToRGBA(img Reader) *image.RGBA {
+  dst := image.NewRGBA(img.Bounds())
+  c.fill(img, dst.Pix, dst.Stride)
+  return dst
+}
+
+func (c *Converter) ToNRGBA(img Reader) *image.NRGBA {
+  dst := image.NewNRGBA(img.Bounds())
+  c.fill(img, dst.Pix, dst.Stride)
+  return dst
+}
+
+// fill writes img as opaque 8-bit RGBA to pix, reading the pixel buffers of
+// the package's own image types directly.
+func (c *Converter) fill(img Reader, pix []uint8, stride int) {
+  b := img.Bounds()
+  set := func(i int, l, a, bb float64) {
+    pix[i+0], pix[i+1], pix[i+2] = c.Lab2rgbClamped(l, a, bb)
+    pix[i+3] = 0xff
+  }
+  switch src := img.(type) {
+  case *Image:
+    for y := b.Min.Y; y < b.Max.Y; y++ {
+      s := src.PixOffset(b.Min.X, y)
+      d := (y - b.Min.Y) * stride
+      for x := b.Min.X; x < b.Max.X; x, s, d = x+1, s+3, d+4 {
+        set(d, src.Pix[s], src.Pix[s+1], src.Pix[s+2])
+      }
+    }
+  case *Image32:
+    for y := b.Min.Y; y < b.Max.Y; y++ {
+      s := src.PixOffset(b.Min.X, y)
+      d := (y - b.Min.Y) * stride
+      for x := b.Min.X; x < b.Max.X; x, s, d = x+1, s+3, d+4 {
+        set(d, float64(src.Pix[s]), float64(src.Pix[s+1]), float64(src.Pix[s+2]))
+      }
+    }
+  default:
+    for y := b.Min.Y; y < b.Max.Y; y++ {
+      d := (y - b.Min.Y) * stride
+      for x := b.Min.X; x < b.Max.X; x, d = x+1, d+4 {
+        lc := img.LabAt(x, y)
+        set(d, lc.L, lc.A, lc.B)
+      }
+    }
+  }
+}
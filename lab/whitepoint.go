@@ -0,0 +1,120 @@
+package lab
+
+// WhitePoint is the XYZ tristimulus value of a reference white, scaled so
+// that Y is 100.
+type WhitePoint struct {
+  X, Y, Z float64
+}
+
+type Illuminant int
+
+const (
+  D65 Illuminant = iota
+  D50
+  D55
+  D75
+  A
+  F2
+  F7
+  F11
+)
+
+type Observer int
+
+const (
+  Observer2  Observer = iota // CIE 1931 2° standard observer
+  Observer10                 // CIE 1964 10° standard observer
+)
+
+var whitePoints = [...][2]WhitePoint{
+  D65: {{RefX, RefY, RefZ}, {94.811, 100.000, 107.304}},
+  D50: {{96.422, 100.000, 82.521}, {96.720, 100.000, 81.427}},
+  D55: {{95.682, 100.000, 92.149}, {95.799, 100.000, 90.926}},
+  D75: {{94.972, 100.000, 122.638}, {94.416, 100.000, 120.641}},
+  A:   {{109.850, 100.000, 35.585}, {111.144, 100.000, 35.200}},
+  F2:  {{99.187, 100.000, 67.395}, {103.280, 100.000, 69.026}},
+  F7:  {{95.044, 100.000, 108.755}, {95.792, 100.000, 107.687}},
+  F11: {{100.966, 100.000, 64.370}, {103.866, 100.000, 65.627}},
+}
+
+// WhitePoint returns the reference white of the illuminant for observer o.
+// Unknown illuminants fall back to D65 and unknown observers to the 2°
+// observer, the same as the zero values.
+func (i Illuminant) WhitePoint(o Observer) WhitePoint {
+  if i < 0 || int(i) >= len(whitePoints) {
+    i = D65
+  }
+  if o != Observer10 {
+    o = Observer2
+  }
+  return whitePoints[i][o]
+}
+
+type matrix [3][3]float64
+
+func (m *matrix) apply(x, y, z float64) (float64, float64, float64) {
+  return m[0][0]*x + m[0][1]*y + m[0][2]*z,
+    m[1][0]*x + m[1][1]*y + m[1][2]*z,
+    m[2][0]*x + m[2][1]*y + m[2][2]*z
+}
+
+func (m *matrix) inverse() *matrix {
+  det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+    m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+    m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+  return &matrix{
+    {
+      (m[1][1]*m[2][2] - m[1][2]*m[2][1]) / det,
+      (m[0][2]*m[2][1] - m[0][1]*m[2][2]) / det,
+      (m[0][1]*m[1][2] - m[0][2]*m[1][1]) / det,
+    },
+    {
+      (m[1][2]*m[2][0] - m[1][0]*m[2][2]) / det,
+      (m[0][0]*m[2][2] - m[0][2]*m[2][0]) / det,
+      (m[0][2]*m[1][0] - m[0][0]*m[1][2]) / det,
+    },
+    {
+      (m[1][0]*m[2][1] - m[1][1]*m[2][0]) / det,
+      (m[0][1]*m[2][0] - m[0][0]*m[2][1]) / det,
+      (m[0][0]*m[1][1] - m[0][1]*m[1][0]) / det,
+    },
+  }
+}
+
+func (m *matrix) mul(n *matrix) *matrix {
+  var p matrix
+  for i := 0; i < 3; i++ {
+    for j := 0; j < 3; j++ {
+      for k := 0; k < 3; k++ {
+        p[i][j] += m[i][k] * n[k][j]
+      }
+    }
+  }
+  return &p
+}
+
+var (
+  bradford = matrix{
+    {0.8951, 0.2664, -0.1614},
+    {-0.7502, 1.7135, 0.0367},
+    {0.0389, -0.0685, 1.0296},
+  }
+  bradfordInverse = matrix{
+    {0.9869929, -0.1470543, 0.1599627},
+    {0.4323053, 0.5183603, 0.0492912},
+    {-0.0085287, 0.0400428, 0.9684867},
+  }
+)
+
+// Bradford returns the matrix that maps XYZ colors seen under the white point
+// from to the corresponding colors under to, by Bradford chromatic adaptation.
+func Bradford(from, to WhitePoint) [3][3]float64 {
+  fr, fg, fb := bradford.apply(from.X, from.Y, from.Z)
+  tr, tg, tb := bradford.apply(to.X, to.Y, to.Z)
+  scale := matrix{
+    {tr / fr, 0, 0},
+    {0, tg / fg, 0},
+    {0, 0, tb / fb},
+  }
+  return *bradfordInverse.mul(scale.mul(&bradford))
+}
@@ -0,0 +1,77 @@
+package lab
+
+import (
+  "math"
+  "testing"
+
+  . "github.com/franela/goblin"
+)
+
+// sRGB red under D50, from the Bradford adapted sRGB matrix.
+const RED_D50_L_, RED_D50_A_, RED_D50_B_ float64 = 54.29, 80.81, 69.89
+
+func TestWhitePoint(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("White points", func() {
+    g.It("Defaults to D65 and the 2° observer", func() {
+      var i Illuminant
+      var o Observer
+      g.Assert(i.WhitePoint(o)).Equal(WhitePoint{RefX, RefY, RefZ})
+    })
+    g.It("Falls back to the defaults for unknown values", func() {
+      g.Assert(Illuminant(-1).WhitePoint(Observer2)).Equal(D65.WhitePoint(Observer2))
+      g.Assert(Illuminant(99).WhitePoint(Observer10)).Equal(D65.WhitePoint(Observer10))
+      g.Assert(A.WhitePoint(Observer(7))).Equal(A.WhitePoint(Observer2))
+    })
+    g.It("Bradford maps one white to the other", func() {
+      from := D65.WhitePoint(Observer2)
+      to := D50.WhitePoint(Observer10)
+      m := matrix(Bradford(from, to))
+      x, y, z := m.apply(from.X, from.Y, from.Z)
+      g.Assert(math.Abs(x-to.X) < 1e-3).IsTrue()
+      g.Assert(math.Abs(y-to.Y) < 1e-3).IsTrue()
+      g.Assert(math.Abs(z-to.Z) < 1e-3).IsTrue()
+    })
+  })
+}
+
+func TestConverter(t *testing.T) {
+  _g := Goblin(t)
+  _g.Describe("Converter", func() {
+    _g.It("Matches Rgb2lab for D65", func() {
+      c := NewConverter(D65.WhitePoint(Observer2))
+      l, a, b := c.Rgb2lab(SEMI_RED_R, SEMI_RED_G, SEMI_RED_B)
+      _g.Assert(l).Equal(SEMI_RED_L_)
+      _g.Assert(a).Equal(SEMI_RED_A_)
+      _g.Assert(b).Equal(SEMI_RED_B_)
+    })
+    _g.It("Maps sRGB white to neutral under D50", func() {
+      c := NewConverter(D50.WhitePoint(Observer2))
+      l, a, b := c.Rgb2lab(WHITE_R, WHITE_G, WHITE_B)
+      _g.Assert(math.Abs(l-100) < 0.05).IsTrue()
+      _g.Assert(math.Abs(a) < 0.05).IsTrue()
+      _g.Assert(math.Abs(b) < 0.05).IsTrue()
+    })
+    _g.It("Converts red under D50", func() {
+      c := NewConverter(D50.WhitePoint(Observer2))
+      l, a, b := c.Rgb2lab(255, 0, 0)
+      _g.Assert(math.Abs(l-RED_D50_L_) < 0.5).IsTrue()
+      _g.Assert(math.Abs(a-RED_D50_A_) < 0.5).IsTrue()
+      _g.Assert(math.Abs(b-RED_D50_B_) < 0.5).IsTrue()
+    })
+    _g.It("Round trips under D50", func() {
+      c := NewConverter(D50.WhitePoint(Observer2))
+      r, g, b := c.Lab2rgb(c.Rgb2lab(SEMI_GREEN_R, SEMI_GREEN_G, SEMI_GREEN_B))
+      _g.Assert(math.Abs(float64(r)-float64(SEMI_GREEN_R)) <= 1).IsTrue()
+      _g.Assert(math.Abs(float64(g)-float64(SEMI_GREEN_G)) <= 1).IsTrue()
+      _g.Assert(math.Abs(float64(b)-float64(SEMI_GREEN_B)) <= 1).IsTrue()
+    })
+    _g.It("Converts images with and without the lookup table alike", func() {
+      c := NewConverter(A.WhitePoint(Observer10))
+      img := testRGBA()
+      fast := c.ImageToLab(img)
+      slow := c.ImageToLab(opaque{img})
+      _g.Assert(sameImage(&fast, &slow, TOLERANCE)).IsTrue()
+    })
+  })
+}
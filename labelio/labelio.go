@@ -0,0 +1,150 @@
+// Package labelio reads and writes superpixel label maps in formats that can
+// be exchanged with other tooling: 16-bit grayscale PNG, RGB-packed 24-bit PNG,
+// 16-bit PGM, raw little-endian int32, NumPy .npy and the Berkeley
+// Segmentation Dataset .seg format.
+package labelio
+
+import (
+  "errors"
+  "fmt"
+  "io"
+  "os"
+  "path/filepath"
+  "strings"
+)
+
+var (
+  ErrSize       = errors.New("labelio: label count does not match width*height")
+  ErrRange      = errors.New("labelio: label out of range for format")
+  ErrFormat     = errors.New("labelio: unknown label map format")
+  ErrBadHeader  = errors.New("labelio: malformed header")
+  ErrDimensions = errors.New("labelio: invalid dimensions")
+)
+
+// maxLabels bounds width*height for maps read from a file, so that a damaged
+// or hostile header cannot make a reader allocate gigabytes up front.
+const maxLabels = 1 << 26
+
+// checkDimensions checks dimensions read from a header.
+func checkDimensions(width, height int) error {
+  if width <= 0 || height <= 0 || width > maxLabels/height {
+    return ErrDimensions
+  }
+  return nil
+}
+
+func checkSize(width, height int, labels []int) error {
+  if width <= 0 || height <= 0 {
+    return ErrDimensions
+  }
+  if len(labels) != width*height {
+    return ErrSize
+  }
+  return nil
+}
+
+func checkRange(labels []int, min, max int) error {
+  for _, l := range labels {
+    if l < min || l > max {
+      return fmt.Errorf("%w: %d", ErrRange, l)
+    }
+  }
+  return nil
+}
+
+func maxLabel(labels []int) int {
+  max := -1
+  for _, l := range labels {
+    if l > max {
+      max = l
+    }
+  }
+  return max
+}
+
+// Save writes labels to filename, choosing the format from its extension:
+// .png (16-bit grayscale when every label fits, RGB-packed otherwise), .pgm,
+// .npy, .raw and .seg.
+func Save(filename string, width, height int, labels []int) error {
+  if err := checkSize(width, height, labels); err != nil {
+    return err
+  }
+  format := strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+  if !IsFormat(format) {
+    return ErrFormat
+  }
+
+  f, err := os.Create(filename)
+  if err != nil {
+    return err
+  }
+  if err := Write(f, format, width, height, labels); err != nil {
+    f.Close()
+    return err
+  }
+  return f.Close()
+}
+
+// Formats lists the names Write accepts, which are also the file extensions
+// Save and Load understand.
+var Formats = []string{"png", "pgm", "npy", "raw", "seg"}
+
+func IsFormat(format string) bool {
+  for _, f := range Formats {
+    if f == format {
+      return true
+    }
+  }
+  return false
+}
+
+// Write writes labels to w in the named format, as Save would for a file
+// with that extension.
+func Write(w io.Writer, format string, width, height int, labels []int) error {
+  if err := checkSize(width, height, labels); err != nil {
+    return err
+  }
+  switch format {
+  case "png":
+    if maxLabel(labels) <= MaxPNG16Label {
+      return WritePNG16(w, width, height, labels)
+    }
+    return WritePNG24(w, width, height, labels)
+  case "pgm":
+    return WritePGM(w, width, height, labels)
+  case "npy":
+    return WriteNPY(w, width, height, labels)
+  case "raw":
+    return WriteRaw(w, width, height, labels)
+  case "seg":
+    return WriteSeg(w, SegHeader{Width: width, Height: height}, labels)
+  }
+  return ErrFormat
+}
+
+// Load reads a label map written by Save.
+func Load(filename string) (width, height int, labels []int, err error) {
+  f, err := os.Open(filename)
+  if err != nil {
+    return 0, 0, nil, err
+  }
+  defer f.Close()
+
+  switch strings.ToLower(filepath.Ext(filename)) {
+  case ".png":
+    return ReadPNG(f)
+  case ".pgm":
+    return ReadPGM(f)
+  case ".npy":
+    return ReadNPY(f)
+  case ".raw":
+    return ReadRaw(f)
+  case ".seg":
+    var header SegHeader
+    if header, labels, err = ReadSeg(f); err != nil {
+      return 0, 0, nil, err
+    }
+    return header.Width, header.Height, labels, nil
+  }
+  return 0, 0, nil, ErrFormat
+}
@@ -0,0 +1,263 @@
+package labelio
+
+import (
+  "bytes"
+  "encoding/binary"
+  "errors"
+  "hash/crc32"
+  "io"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+
+  . "github.com/franela/goblin"
+)
+
+const (
+  WIDTH  = 7
+  HEIGHT = 5
+)
+
+func makeLabels(scale int) []int {
+  labels := make([]int, WIDTH*HEIGHT)
+  for i := range labels {
+    labels[i] = i * scale
+  }
+  return labels
+}
+
+func TestRoundtrip(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("Roundtrip", func() {
+    g.It("16-bit PNG", func() {
+      in := makeLabels(1873)
+      var buf bytes.Buffer
+      g.Assert(WritePNG16(&buf, WIDTH, HEIGHT, in)).Equal(nil)
+      w, h, out, err := ReadPNG16(&buf)
+      g.Assert(err).Equal(nil)
+      g.Assert(w).Equal(WIDTH)
+      g.Assert(h).Equal(HEIGHT)
+      g.Assert(out).Equal(in)
+    })
+    g.It("24-bit PNG", func() {
+      in := makeLabels(479001)
+      var buf bytes.Buffer
+      g.Assert(WritePNG24(&buf, WIDTH, HEIGHT, in)).Equal(nil)
+      w, h, out, err := ReadPNG24(&buf)
+      g.Assert(err).Equal(nil)
+      g.Assert(w).Equal(WIDTH)
+      g.Assert(h).Equal(HEIGHT)
+      g.Assert(out).Equal(in)
+    })
+    g.It("16-bit PGM", func() {
+      in := makeLabels(1873)
+      var buf bytes.Buffer
+      g.Assert(WritePGM(&buf, WIDTH, HEIGHT, in)).Equal(nil)
+      w, h, out, err := ReadPGM(&buf)
+      g.Assert(err).Equal(nil)
+      g.Assert(w).Equal(WIDTH)
+      g.Assert(h).Equal(HEIGHT)
+      g.Assert(out).Equal(in)
+    })
+    g.It("Plain PGM at a small maxval", func() {
+      w, h, out, err := ReadPGM(bytes.NewReader([]byte("P2\n3 1\n5\n0 5 3\n")))
+      g.Assert(err).Equal(nil)
+      g.Assert([]int{w, h}).Equal([]int{3, 1})
+      g.Assert(out).Equal([]int{0, 5, 3})
+    })
+    g.It("Raw", func() {
+      in := makeLabels(104729)
+      in[3] = -1
+      var buf bytes.Buffer
+      g.Assert(WriteRaw(&buf, WIDTH, HEIGHT, in)).Equal(nil)
+      g.Assert(buf.Len()).Equal(12 + 4*WIDTH*HEIGHT)
+      w, h, out, err := ReadRaw(&buf)
+      g.Assert(err).Equal(nil)
+      g.Assert(w).Equal(WIDTH)
+      g.Assert(h).Equal(HEIGHT)
+      g.Assert(out).Equal(in)
+    })
+    g.It("NPY", func() {
+      in := makeLabels(104729)
+      var buf bytes.Buffer
+      g.Assert(WriteNPY(&buf, WIDTH, HEIGHT, in)).Equal(nil)
+      g.Assert((buf.Len() - 4*WIDTH*HEIGHT) % 64).Equal(0)
+      w, h, out, err := ReadNPY(&buf)
+      g.Assert(err).Equal(nil)
+      g.Assert(w).Equal(WIDTH)
+      g.Assert(h).Equal(HEIGHT)
+      g.Assert(out).Equal(in)
+    })
+    g.It("Save and Load", func() {
+      dir, err := os.MkdirTemp("", "labelio")
+      g.Assert(err).Equal(nil)
+      defer os.RemoveAll(dir)
+
+      for _, name := range []string{"small.png", "labels.pgm", "labels.npy", "labels.raw", "labels.seg"} {
+        in := makeLabels(3)
+        path := filepath.Join(dir, name)
+        g.Assert(Save(path, WIDTH, HEIGHT, in)).Equal(nil)
+        w, h, out, err := Load(path)
+        g.Assert(err).Equal(nil)
+        g.Assert(w).Equal(WIDTH)
+        g.Assert(h).Equal(HEIGHT)
+        g.Assert(out).Equal(in)
+      }
+
+      // Too large for 16 bits, so Save falls back to the packed encoding.
+      in := makeLabels(479001)
+      path := filepath.Join(dir, "large.png")
+      g.Assert(Save(path, WIDTH, HEIGHT, in)).Equal(nil)
+      _, _, out, err := Load(path)
+      g.Assert(err).Equal(nil)
+      g.Assert(out).Equal(in)
+    })
+  })
+}
+
+func TestNPYHeaders(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("NPY headers", func() {
+    g.It("Reads Fortran ordered uint16 arrays", func() {
+      header := "{'descr': '<u2', 'fortran_order': True, 'shape': (2, 3), }"
+      var buf bytes.Buffer
+      buf.WriteString(npyMagic)
+      buf.Write([]byte{1, 0, byte(len(header)), 0})
+      buf.WriteString(header)
+      // Columns of [[0 1 2] [3 4 5]].
+      buf.Write([]byte{0, 0, 3, 0, 1, 0, 4, 0, 2, 0, 5, 0})
+
+      w, h, out, err := ReadNPY(&buf)
+      g.Assert(err).Equal(nil)
+      g.Assert(w).Equal(3)
+      g.Assert(h).Equal(2)
+      g.Assert(out).Equal([]int{0, 1, 2, 3, 4, 5})
+    })
+    g.It("Rejects float arrays", func() {
+      header := "{'descr': '<f8', 'fortran_order': False, 'shape': (1, 1), }"
+      var buf bytes.Buffer
+      buf.WriteString(npyMagic)
+      buf.Write([]byte{1, 0, byte(len(header)), 0})
+      buf.WriteString(header)
+      buf.Write(make([]byte, 8))
+
+      _, _, _, err := ReadNPY(&buf)
+      g.Assert(errors.Is(err, ErrFormat)).IsTrue()
+    })
+  })
+}
+
+const BSDS_SEG = `format ascii cr
+date Thu Apr 26 17:39:26 2001
+image 2092
+user 1130
+width 4
+height 3
+segments 3
+gray 1
+invert 0
+flipflop 0
+data
+0 0 0 3
+1 1 0 1
+2 1 2 3
+2 2 0 3
+`
+
+func TestSeg(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("BSDS .seg", func() {
+    g.It("Reads ground truth", func() {
+      header, labels, err := ReadSeg(strings.NewReader(BSDS_SEG))
+      g.Assert(err).Equal(nil)
+      g.Assert(header).Equal(SegHeader{
+        Format:   "ascii cr",
+        Date:     "Thu Apr 26 17:39:26 2001",
+        Image:    "2092",
+        User:     "1130",
+        Width:    4,
+        Height:   3,
+        Segments: 3,
+        Gray:     true,
+      })
+      g.Assert(labels).Equal([]int{0, 0, 0, 0, 1, 1, 2, 2, 2, 2, 2, 2})
+    })
+    g.It("Writes the same runs", func() {
+      header, labels, _ := ReadSeg(strings.NewReader(BSDS_SEG))
+      var buf bytes.Buffer
+      g.Assert(WriteSeg(&buf, header, labels)).Equal(nil)
+      g.Assert(buf.String()).Equal(BSDS_SEG)
+    })
+    g.It("Fills in the segment count", func() {
+      var buf bytes.Buffer
+      in := makeLabels(1)
+      g.Assert(WriteSeg(&buf, SegHeader{Width: WIDTH, Height: HEIGHT}, in)).Equal(nil)
+      header, out, err := ReadSeg(&buf)
+      g.Assert(err).Equal(nil)
+      g.Assert(header.Segments).Equal(WIDTH * HEIGHT)
+      g.Assert(out).Equal(in)
+    })
+    g.It("Rejects runs outside the image", func() {
+      _, _, err := ReadSeg(strings.NewReader("width 2\nheight 2\ndata\n0 0 0 2\n"))
+      g.Assert(errors.Is(err, ErrBadHeader)).IsTrue()
+    })
+  })
+}
+
+func TestErrors(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("Errors", func() {
+    g.It("Rejects mismatched sizes", func() {
+      var buf bytes.Buffer
+      g.Assert(WriteRaw(&buf, WIDTH, HEIGHT, make([]int, 3))).Equal(ErrSize)
+    })
+    g.It("Rejects labels that do not fit in 16 bits", func() {
+      var buf bytes.Buffer
+      in := makeLabels(1)
+      in[0] = MaxPNG16Label + 1
+      g.Assert(errors.Is(WritePNG16(&buf, WIDTH, HEIGHT, in), ErrRange)).IsTrue()
+    })
+    g.It("Rejects negative labels in PNG", func() {
+      var buf bytes.Buffer
+      in := makeLabels(1)
+      in[0] = -1
+      g.Assert(errors.Is(WritePNG24(&buf, WIDTH, HEIGHT, in), ErrRange)).IsTrue()
+    })
+    g.It("Rejects bad raw magic", func() {
+      _, _, _, err := ReadRaw(bytes.NewReader(make([]byte, 12)))
+      g.Assert(err).Equal(ErrBadHeader)
+    })
+    g.It("Rejects huge dimensions before allocating", func() {
+      raw := []byte(RawMagic + "\xff\xff\xff\x7f\xff\xff\xff\x7f")
+      _, _, _, err := ReadRaw(bytes.NewReader(raw))
+      g.Assert(err).Equal(ErrDimensions)
+
+      header := "{'descr': '<i4', 'fortran_order': False, 'shape': (100000, 100000), }"
+      npy := append([]byte(npyMagic+"\x01\x00"), byte(len(header)), 0)
+      npy = append(npy, header...)
+      _, _, _, err = ReadNPY(bytes.NewReader(npy))
+      g.Assert(err).Equal(ErrDimensions)
+
+      _, _, err = ReadSeg(strings.NewReader("width 100000\nheight 100000\ndata\n"))
+      g.Assert(err).Equal(ErrDimensions)
+
+      var buf bytes.Buffer
+      WritePNG16(&buf, 1, 1, []int{0})
+      huge := buf.Bytes()
+      // Patch the IHDR width and height and its checksum.
+      binary.BigEndian.PutUint32(huge[16:], 100000)
+      binary.BigEndian.PutUint32(huge[20:], 100000)
+      binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+      for _, read := range []func(io.Reader) (int, int, []int, error){ReadPNG, ReadPNG16, ReadPNG24} {
+        _, _, _, err = read(bytes.NewReader(huge))
+        g.Assert(err).Equal(ErrDimensions)
+      }
+    })
+    g.It("Rejects oversized NumPy headers", func() {
+      npy := []byte(npyMagic + "\x02\x00\xff\xff\xff\x7f")
+      _, _, _, err := ReadNPY(bytes.NewReader(npy))
+      g.Assert(err).Equal(ErrBadHeader)
+    })
+  })
+}
@@ -0,0 +1,165 @@
+package labelio
+
+import (
+  "bufio"
+  "encoding/binary"
+  "fmt"
+  "io"
+  "math"
+  "regexp"
+  "strconv"
+  "strings"
+)
+
+const npyMagic = "\x93NUMPY"
+
+// npyMaxHeader bounds the header length field, which real files keep to a few
+// hundred bytes.
+const npyMaxHeader = 1 << 16
+
+var (
+  npyDescr   = regexp.MustCompile(`'descr'\s*:\s*'([^']*)'`)
+  npyFortran = regexp.MustCompile(`'fortran_order'\s*:\s*(True|False)`)
+  npyShape   = regexp.MustCompile(`'shape'\s*:\s*\(\s*(\d+)\s*,\s*(\d+)\s*,?\s*\)`)
+)
+
+// WriteNPY writes labels as a (height, width) int32 NumPy array, loadable
+// with numpy.load.
+func WriteNPY(w io.Writer, width, height int, labels []int) error {
+  if err := checkSize(width, height, labels); err != nil {
+    return err
+  }
+  if err := checkRange(labels, math.MinInt32, math.MaxInt32); err != nil {
+    return err
+  }
+
+  dict := fmt.Sprintf("{'descr': '<i4', 'fortran_order': False, 'shape': (%d, %d), }", height, width)
+  // Magic, version and header length take 10 bytes; the header is padded
+  // with spaces so the data starts on a 64 byte boundary.
+  pad := 64 - (10+len(dict)+1)%64
+  if pad == 64 {
+    pad = 0
+  }
+  dict += strings.Repeat(" ", pad) + "\n"
+
+  bw := bufio.NewWriter(w)
+  bw.WriteString(npyMagic)
+  bw.Write([]byte{1, 0})
+  var hlen [2]byte
+  binary.LittleEndian.PutUint16(hlen[:], uint16(len(dict)))
+  bw.Write(hlen[:])
+  bw.WriteString(dict)
+  if err := writeInt32s(bw, labels); err != nil {
+    return err
+  }
+  return bw.Flush()
+}
+
+// ReadNPY reads a two dimensional integer NumPy array. Signed and unsigned
+// integer dtypes of either byte order are accepted, as is Fortran ordering.
+func ReadNPY(r io.Reader) (width, height int, labels []int, err error) {
+  br := bufio.NewReader(r)
+  var pre [8]byte
+  if _, err = io.ReadFull(br, pre[:]); err != nil {
+    return 0, 0, nil, err
+  }
+  if string(pre[:6]) != npyMagic {
+    return 0, 0, nil, ErrBadHeader
+  }
+
+  var hlen int
+  switch pre[6] {
+  case 1:
+    var b [2]byte
+    if _, err = io.ReadFull(br, b[:]); err != nil {
+      return 0, 0, nil, err
+    }
+    hlen = int(binary.LittleEndian.Uint16(b[:]))
+  case 2, 3:
+    var b [4]byte
+    if _, err = io.ReadFull(br, b[:]); err != nil {
+      return 0, 0, nil, err
+    }
+    hlen = int(binary.LittleEndian.Uint32(b[:]))
+  default:
+    return 0, 0, nil, ErrBadHeader
+  }
+
+  if hlen > npyMaxHeader {
+    return 0, 0, nil, ErrBadHeader
+  }
+  header := make([]byte, hlen)
+  if _, err = io.ReadFull(br, header); err != nil {
+    return 0, 0, nil, err
+  }
+
+  descr := npyDescr.FindSubmatch(header)
+  fortran := npyFortran.FindSubmatch(header)
+  shape := npyShape.FindSubmatch(header)
+  if descr == nil || fortran == nil || shape == nil {
+    return 0, 0, nil, ErrBadHeader
+  }
+  height, herr := strconv.Atoi(string(shape[1]))
+  width, werr := strconv.Atoi(string(shape[2]))
+  if herr != nil || werr != nil {
+    return 0, 0, nil, ErrDimensions
+  }
+  if err = checkDimensions(width, height); err != nil {
+    return 0, 0, nil, err
+  }
+
+  decode, size, err := npyDecoder(string(descr[1]))
+  if err != nil {
+    return 0, 0, nil, err
+  }
+
+  labels = make([]int, width*height)
+  buf := make([]byte, size)
+  for i := range labels {
+    if _, err = io.ReadFull(br, buf); err != nil {
+      return 0, 0, nil, err
+    }
+    if string(fortran[1]) == "True" {
+      // Column-major: element i is at row i%height, column i/height.
+      labels[(i%height)*width+i/height] = decode(buf)
+    } else {
+      labels[i] = decode(buf)
+    }
+  }
+  return width, height, labels, nil
+}
+
+func npyDecoder(descr string) (decode func([]byte) int, size int, err error) {
+  if len(descr) != 3 {
+    return nil, 0, fmt.Errorf("%w: dtype %q", ErrFormat, descr)
+  }
+
+  var order binary.ByteOrder = binary.LittleEndian
+  switch descr[0] {
+  case '<', '|', '=':
+  case '>':
+    order = binary.BigEndian
+  default:
+    return nil, 0, fmt.Errorf("%w: dtype %q", ErrFormat, descr)
+  }
+
+  switch descr[1:] {
+  case "u1":
+    return func(b []byte) int { return int(b[0]) }, 1, nil
+  case "i1":
+    return func(b []byte) int { return int(int8(b[0])) }, 1, nil
+  case "u2":
+    return func(b []byte) int { return int(order.Uint16(b)) }, 2, nil
+  case "i2":
+    return func(b []byte) int { return int(int16(order.Uint16(b))) }, 2, nil
+  case "u4":
+    return func(b []byte) int { return int(order.Uint32(b)) }, 4, nil
+  case "i4":
+    return func(b []byte) int { return int(int32(order.Uint32(b))) }, 4, nil
+  case "u8":
+    return func(b []byte) int { return int(order.Uint64(b)) }, 8, nil
+  case "i8":
+    return func(b []byte) int { return int(int64(order.Uint64(b))) }, 8, nil
+  }
+  return nil, 0, fmt.Errorf("%w: dtype %q", ErrFormat, descr)
+}
@@ -0,0 +1,47 @@
+package labelio
+
+import (
+  "image"
+  "image/color"
+  "io"
+
+  "github.com/kurige/SLIC/netpbm"
+)
+
+const MaxPGMLabel = 1<<16 - 1
+
+// WritePGM encodes labels as a raw 16-bit PGM with a maxval of 65535, one
+// label per pixel.
+func WritePGM(w io.Writer, width, height int, labels []int) error {
+  if err := checkSize(width, height, labels); err != nil {
+    return err
+  }
+  if err := checkRange(labels, 0, MaxPGMLabel); err != nil {
+    return err
+  }
+
+  img := image.NewGray16(image.Rect(0, 0, width, height))
+  for y := 0; y < height; y++ {
+    for x := 0; x < width; x++ {
+      img.SetGray16(x, y, color.Gray16{uint16(labels[y*width+x])})
+    }
+  }
+  return netpbm.Encode(w, img, &netpbm.Options{Format: netpbm.PGM, Maxval: 65535})
+}
+
+// ReadPGM decodes a plain or raw PGM label map at any maxval. Sample values
+// are taken as labels without scaling.
+func ReadPGM(r io.Reader) (width, height int, labels []int, err error) {
+  s, err := netpbm.ReadSamples(r)
+  if err != nil {
+    return 0, 0, nil, err
+  }
+  if s.Depth != 1 {
+    return 0, 0, nil, ErrFormat
+  }
+  labels = make([]int, len(s.Pix))
+  for i, v := range s.Pix {
+    labels[i] = int(v)
+  }
+  return s.Width, s.Height, labels, nil
+}
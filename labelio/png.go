@@ -0,0 +1,137 @@
+package labelio
+
+import (
+  "bytes"
+  "image"
+  "image/color"
+  "image/png"
+  "io"
+)
+
+const (
+  MaxPNG16Label = 1<<16 - 1
+  MaxPNG24Label = 1<<24 - 1
+)
+
+// WritePNG16 encodes labels as a 16-bit grayscale PNG, one label per pixel.
+func WritePNG16(w io.Writer, width, height int, labels []int) error {
+  if err := checkSize(width, height, labels); err != nil {
+    return err
+  }
+  if err := checkRange(labels, 0, MaxPNG16Label); err != nil {
+    return err
+  }
+
+  img := image.NewGray16(image.Rect(0, 0, width, height))
+  for y := 0; y < height; y++ {
+    for x := 0; x < width; x++ {
+      img.SetGray16(x, y, color.Gray16{uint16(labels[y*width+x])})
+    }
+  }
+  return png.Encode(w, img)
+}
+
+// WritePNG24 encodes labels as an 8-bit RGB PNG with each label packed as
+// R<<16 | G<<8 | B.
+func WritePNG24(w io.Writer, width, height int, labels []int) error {
+  if err := checkSize(width, height, labels); err != nil {
+    return err
+  }
+  if err := checkRange(labels, 0, MaxPNG24Label); err != nil {
+    return err
+  }
+
+  img := image.NewRGBA(image.Rect(0, 0, width, height))
+  for y := 0; y < height; y++ {
+    for x := 0; x < width; x++ {
+      l := labels[y*width+x]
+      img.SetRGBA(x, y, color.RGBA{uint8(l >> 16), uint8(l >> 8), uint8(l), 255})
+    }
+  }
+  return png.Encode(w, img)
+}
+
+// ReadPNG16 decodes a grayscale PNG written by WritePNG16.
+func ReadPNG16(r io.Reader) (width, height int, labels []int, err error) {
+  img, err := decodePNG(r)
+  if err != nil {
+    return 0, 0, nil, err
+  }
+  return grayLabels(img)
+}
+
+// ReadPNG24 decodes an RGB-packed PNG written by WritePNG24.
+func ReadPNG24(r io.Reader) (width, height int, labels []int, err error) {
+  img, err := decodePNG(r)
+  if err != nil {
+    return 0, 0, nil, err
+  }
+  width, height, labels = packedLabels(img)
+  return width, height, labels, nil
+}
+
+// ReadPNG decodes either PNG flavour, treating grayscale images as
+// WritePNG16 output and everything else as RGB-packed.
+func ReadPNG(r io.Reader) (width, height int, labels []int, err error) {
+  img, err := decodePNG(r)
+  if err != nil {
+    return 0, 0, nil, err
+  }
+  switch img.(type) {
+  case *image.Gray16, *image.Gray:
+    return grayLabels(img)
+  }
+  width, height, labels = packedLabels(img)
+  return width, height, labels, nil
+}
+
+// decodePNG checks the dimensions in the PNG header before decoding, so that a
+// small file claiming a huge image is rejected without allocating for it.
+func decodePNG(r io.Reader) (image.Image, error) {
+  var header bytes.Buffer
+  cfg, err := png.DecodeConfig(io.TeeReader(r, &header))
+  if err != nil {
+    return nil, err
+  }
+  if err := checkDimensions(cfg.Width, cfg.Height); err != nil {
+    return nil, err
+  }
+  return png.Decode(io.MultiReader(&header, r))
+}
+
+func grayLabels(img image.Image) (width, height int, labels []int, err error) {
+  b := img.Bounds()
+  width, height = b.Dx(), b.Dy()
+  labels = make([]int, width*height)
+
+  switch img := img.(type) {
+  case *image.Gray16:
+    for y := 0; y < height; y++ {
+      for x := 0; x < width; x++ {
+        labels[y*width+x] = int(img.Gray16At(b.Min.X+x, b.Min.Y+y).Y)
+      }
+    }
+  case *image.Gray:
+    for y := 0; y < height; y++ {
+      for x := 0; x < width; x++ {
+        labels[y*width+x] = int(img.GrayAt(b.Min.X+x, b.Min.Y+y).Y)
+      }
+    }
+  default:
+    return 0, 0, nil, ErrFormat
+  }
+  return width, height, labels, nil
+}
+
+func packedLabels(img image.Image) (width, height int, labels []int) {
+  b := img.Bounds()
+  width, height = b.Dx(), b.Dy()
+  labels = make([]int, width*height)
+  for y := 0; y < height; y++ {
+    for x := 0; x < width; x++ {
+      r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+      labels[y*width+x] = int(r>>8)<<16 | int(g>>8)<<8 | int(bl>>8)
+    }
+  }
+  return
+}
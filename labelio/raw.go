@@ -0,0 +1,103 @@
+package labelio
+
+import (
+  "bufio"
+  "encoding/binary"
+  "io"
+  "math"
+)
+
+// RawMagic identifies label maps written by WriteRaw. The magic is followed
+// by the width and height as little-endian uint32 and then width*height
+// little-endian int32 labels in row-major order.
+const RawMagic = "SLBL"
+
+func WriteRaw(w io.Writer, width, height int, labels []int) error {
+  if err := checkSize(width, height, labels); err != nil {
+    return err
+  }
+  if err := checkRange(labels, math.MinInt32, math.MaxInt32); err != nil {
+    return err
+  }
+
+  bw := bufio.NewWriter(w)
+  if err := WriteRawHeader(bw, width, height); err != nil {
+    return err
+  }
+  if err := writeInt32s(bw, labels); err != nil {
+    return err
+  }
+  return bw.Flush()
+}
+
+// WriteRawHeader and WriteRawLabels let label maps too large to hold in memory
+// be written incrementally: the header first, then the labels in row-major
+// order over any number of calls.
+func WriteRawHeader(w io.Writer, width, height int) error {
+  if width <= 0 || height <= 0 {
+    return ErrDimensions
+  }
+  var header [12]byte
+  copy(header[:4], RawMagic)
+  binary.LittleEndian.PutUint32(header[4:], uint32(width))
+  binary.LittleEndian.PutUint32(header[8:], uint32(height))
+  _, err := w.Write(header[:])
+  return err
+}
+
+func WriteRawLabels(w io.Writer, labels []int) error {
+  if err := checkRange(labels, math.MinInt32, math.MaxInt32); err != nil {
+    return err
+  }
+  return writeInt32s(w, labels)
+}
+
+// ReadRawLabels fills labels from a stream of little-endian int32 values, such
+// as the data following a raw header.
+func ReadRawLabels(r io.Reader, labels []int) error {
+  return readInt32s(r, labels)
+}
+
+func ReadRaw(r io.Reader) (width, height int, labels []int, err error) {
+  br := bufio.NewReader(r)
+  var header [12]byte
+  if _, err = io.ReadFull(br, header[:]); err != nil {
+    return 0, 0, nil, err
+  }
+  if string(header[:4]) != RawMagic {
+    return 0, 0, nil, ErrBadHeader
+  }
+  width = int(binary.LittleEndian.Uint32(header[4:]))
+  height = int(binary.LittleEndian.Uint32(header[8:]))
+  if err = checkDimensions(width, height); err != nil {
+    return 0, 0, nil, err
+  }
+
+  labels = make([]int, width*height)
+  if err = readInt32s(br, labels); err != nil {
+    return 0, 0, nil, err
+  }
+  return width, height, labels, nil
+}
+
+func writeInt32s(w io.Writer, labels []int) error {
+  var buf [4]byte
+  for _, l := range labels {
+    binary.LittleEndian.PutUint32(buf[:], uint32(int32(l)))
+    if _, err := w.Write(buf[:]); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+func readInt32s(r io.Reader, labels []int) error {
+  var buf [4]byte
+  for i := range labels {
+    if _, err := io.ReadFull(r, buf[:]); err != nil {
+      return err
+    }
+    labels[i] = int(int32(binary.LittleEndian.Uint32(buf[:])))
+  }
+  return nil
+}
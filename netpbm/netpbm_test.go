@@ -0,0 +1,149 @@
+package netpbm
+
+import (
+  "bytes"
+  "image"
+  "image/color"
+  "strings"
+  "testing"
+
+  . "github.com/franela/goblin"
+)
+
+func testRGBA64() *image.RGBA64 {
+  img := image.NewRGBA64(image.Rect(0, 0, 7, 5))
+  for y := 0; y < 5; y++ {
+    for x := 0; x < 7; x++ {
+      img.SetRGBA64(x, y, color.RGBA64{uint16(x * 9000), uint16(y * 13000), uint16(x*y*1000 + 7), 0xffff})
+    }
+  }
+  return img
+}
+
+func roundTrip(img image.Image, o *Options) (image.Image, string, error) {
+  var buf bytes.Buffer
+  if err := Encode(&buf, img, o); err != nil {
+    return nil, "", err
+  }
+  return image.Decode(&buf)
+}
+
+func samePixels(a, b image.Image) bool {
+  r := a.Bounds()
+  if r != b.Bounds() {
+    return false
+  }
+  for y := r.Min.Y; y < r.Max.Y; y++ {
+    for x := r.Min.X; x < r.Max.X; x++ {
+      if color.NRGBA64Model.Convert(a.At(x, y)) != color.NRGBA64Model.Convert(b.At(x, y)) {
+        return false
+      }
+    }
+  }
+  return true
+}
+
+func TestNetpbm(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("Round trips", func() {
+    g.It("Keeps 16-bit RGB in raw and plain PPM", func() {
+      src := testRGBA64()
+      for _, plain := range []bool{false, true} {
+        img, name, err := roundTrip(src, &Options{Plain: plain})
+        g.Assert(err).Equal(nil)
+        g.Assert(name).Equal("ppm")
+        g.Assert(img.(*image.RGBA64).Pix).Equal(src.Pix)
+      }
+    })
+    g.It("Keeps 8-bit gray in PGM", func() {
+      src := image.NewGray(image.Rect(0, 0, 40, 3))
+      for i := range src.Pix {
+        src.Pix[i] = uint8(i * 2)
+      }
+      for _, plain := range []bool{false, true} {
+        img, name, err := roundTrip(src, &Options{Plain: plain})
+        g.Assert(err).Equal(nil)
+        g.Assert(name).Equal("pgm")
+        g.Assert(img.(*image.Gray).Pix).Equal(src.Pix)
+      }
+    })
+    g.It("Keeps 16-bit gray in PGM", func() {
+      src := image.NewGray16(image.Rect(0, 0, 3, 2))
+      for i := 0; i < 6; i++ {
+        src.SetGray16(i%3, i/3, color.Gray16{uint16(i * 11111)})
+      }
+      img, _, err := roundTrip(src, nil)
+      g.Assert(err).Equal(nil)
+      g.Assert(img.(*image.Gray16).Pix).Equal(src.Pix)
+    })
+    g.It("Keeps alpha in PAM", func() {
+      src := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+      for i := range src.Pix {
+        src.Pix[i] = uint8(i * 3)
+      }
+      img, name, err := roundTrip(src, &Options{Format: PAM})
+      g.Assert(err).Equal(nil)
+      g.Assert(name).Equal("pam")
+      g.Assert(img.(*image.NRGBA).Pix).Equal(src.Pix)
+
+      gray := image.NewGray16(image.Rect(0, 0, 2, 2))
+      gray.SetGray16(1, 1, color.Gray16{40000})
+      img, _, err = roundTrip(gray, &Options{Format: PAM})
+      g.Assert(err).Equal(nil)
+      g.Assert(img.(*image.Gray16).Pix).Equal(gray.Pix)
+    })
+    g.It("Scales to and from other maxvals", func() {
+      src := testRGBA64()
+      img, _, err := roundTrip(src, &Options{Maxval: 1023})
+      g.Assert(err).Equal(nil)
+      out := img.(*image.RGBA64)
+      for i := range src.Pix {
+        if i%2 == 0 {
+          a, b := int(src.Pix[i])<<8|int(src.Pix[i+1]), int(out.Pix[i])<<8|int(out.Pix[i+1])
+          g.Assert(a-b <= 64 && b-a <= 64).IsTrue()
+        }
+      }
+    })
+  })
+  g.Describe("Decoding", func() {
+    g.It("Reads comments and small maxvals", func() {
+      img, err := Decode(bytes.NewReader([]byte("P2\n# a comment\n3 1 # trailing\n4\n0 2 4\n")))
+      g.Assert(err).Equal(nil)
+      g.Assert(img.(*image.Gray).Pix).Equal([]uint8{0, 128, 255})
+    })
+    g.It("Reads a PAM header", func() {
+      data := []byte("P7\nWIDTH 2\nHEIGHT 1\nDEPTH 3\nMAXVAL 255\nTUPLTYPE RGB\nENDHDR\n\x01\x02\x03\x04\x05\x06")
+      cfg, name, err := image.DecodeConfig(bytes.NewReader(data))
+      g.Assert(err).Equal(nil)
+      g.Assert(name).Equal("pam")
+      g.Assert([]int{cfg.Width, cfg.Height}).Equal([]int{2, 1})
+      g.Assert(cfg.ColorModel == color.RGBAModel).IsTrue()
+      img, err := Decode(bytes.NewReader(data))
+      g.Assert(err).Equal(nil)
+      g.Assert(img.(*image.RGBA).Pix).Equal([]uint8{1, 2, 3, 255, 4, 5, 6, 255})
+    })
+    g.It("Rejects bad input", func() {
+      _, err := Decode(bytes.NewReader([]byte("P9\n1 1\n255\n\x00")))
+      g.Assert(err).Equal(ErrFormat)
+      _, err = Decode(bytes.NewReader([]byte("P5\n1 x\n255\n\x00")))
+      g.Assert(err).Equal(ErrHeader)
+      _, err = Decode(bytes.NewReader([]byte("P5\n1 1\n7\n\x09")))
+      g.Assert(err).Equal(ErrSample)
+      _, err = Decode(bytes.NewReader([]byte("P5\n100000 100000\n255\n")))
+      g.Assert(err).Equal(ErrTooLarge)
+      _, err = Decode(bytes.NewReader([]byte("P6\n2 2\n255\n\x00\x00")))
+      g.Assert(err != nil).IsTrue()
+    })
+    g.It("Honors a lowered MaxSamples", func() {
+      defer func(n int64) { MaxSamples = n }(MaxSamples)
+      MaxSamples = 11
+      _, err := Decode(bytes.NewReader([]byte("P6\n2 2\n255\n" + strings.Repeat("\x00", 12))))
+      g.Assert(err).Equal(ErrTooLarge)
+      _, _, err = image.DecodeConfig(bytes.NewReader([]byte("P6\n2 2\n255\n")))
+      g.Assert(err).Equal(ErrTooLarge)
+      MaxSamples = 12
+      _, err = Decode(bytes.NewReader([]byte("P6\n2 2\n255\n" + strings.Repeat("\x00", 12))))
+      g.Assert(err).Equal(nil)
+    })
+  })
+}
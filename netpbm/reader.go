@@ -0,0 +1,385 @@
+// Package netpbm decodes and encodes the Netpbm image formats: plain and raw
+// PGM (P2, P5) and PPM (P3, P6), and PAM (P7), at 8 and 16 bits per sample.
+// Importing it registers the decoders with the image package.
+package netpbm
+
+import (
+  "bufio"
+  "errors"
+  "fmt"
+  "image"
+  "image/color"
+  "io"
+  "strconv"
+  "strings"
+)
+
+var (
+  ErrFormat   = errors.New("netpbm: not a PGM, PPM or PAM image")
+  ErrHeader   = errors.New("netpbm: malformed header")
+  ErrTooLarge = errors.New("netpbm: image too large")
+  ErrSample   = errors.New("netpbm: sample exceeds maxval")
+)
+
+// MaxSamples bounds width*height*depth, so that a corrupt or hostile header
+// cannot make the decoder allocate without limit. Samples take two bytes each,
+// so the default allows 512MB, enough for a 16-bit RGB image of 89 million
+// pixels. Images over the limit fail with ErrTooLarge.
+var MaxSamples int64 = 1 << 28
+
+func init() {
+  image.RegisterFormat("pgm", "P2", Decode, DecodeConfig)
+  image.RegisterFormat("pgm", "P5", Decode, DecodeConfig)
+  image.RegisterFormat("ppm", "P3", Decode, DecodeConfig)
+  image.RegisterFormat("ppm", "P6", Decode, DecodeConfig)
+  image.RegisterFormat("pam", "P7", Decode, DecodeConfig)
+}
+
+type header struct {
+  magic         string
+  width, height int
+  depth         int
+  maxval        int
+  tupltype      string
+}
+
+func (h *header) plain() bool { return h.magic == "P2" || h.magic == "P3" }
+
+func (h *header) model() color.Model {
+  deep := h.maxval > 255
+  switch h.depth {
+  case 1:
+    if deep {
+      return color.Gray16Model
+    }
+    return color.GrayModel
+  case 3:
+    if deep {
+      return color.RGBA64Model
+    }
+    return color.RGBAModel
+  }
+  if deep {
+    return color.NRGBA64Model
+  }
+  return color.NRGBAModel
+}
+
+// reader tokenizes the header, skipping comments.
+type reader struct {
+  *bufio.Reader
+}
+
+func (r reader) skipSpace() error {
+  for {
+    b, err := r.ReadByte()
+    if err != nil {
+      return err
+    }
+    switch {
+    case b == '#':
+      if _, err := r.ReadString('\n'); err != nil {
+        return err
+      }
+    case b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\v' || b == '\f':
+    default:
+      return r.UnreadByte()
+    }
+  }
+}
+
+func (r reader) token() (string, error) {
+  if err := r.skipSpace(); err != nil {
+    return "", err
+  }
+  var tok []byte
+  for {
+    b, err := r.ReadByte()
+    if err == io.EOF && len(tok) > 0 {
+      return string(tok), nil
+    }
+    if err != nil {
+      return "", err
+    }
+    if b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\v' || b == '\f' || b == '#' {
+      return string(tok), r.UnreadByte()
+    }
+    tok = append(tok, b)
+  }
+}
+
+func (r reader) int() (int, error) {
+  tok, err := r.token()
+  if err != nil {
+    return 0, ErrHeader
+  }
+  v, err := strconv.Atoi(tok)
+  if err != nil || v < 0 {
+    return 0, ErrHeader
+  }
+  return v, nil
+}
+
+func readHeader(r reader) (*header, error) {
+  var magic [2]byte
+  if _, err := io.ReadFull(r, magic[:]); err != nil {
+    return nil, ErrFormat
+  }
+  h := &header{magic: string(magic[:])}
+  switch h.magic {
+  case "P2", "P5":
+    h.depth = 1
+  case "P3", "P6":
+    h.depth = 3
+  case "P7":
+    if err := readPAMHeader(r, h); err != nil {
+      return nil, err
+    }
+  default:
+    return nil, ErrFormat
+  }
+
+  if h.magic != "P7" {
+    var err error
+    if h.width, err = r.int(); err != nil {
+      return nil, err
+    }
+    if h.height, err = r.int(); err != nil {
+      return nil, err
+    }
+    if h.maxval, err = r.int(); err != nil {
+      return nil, err
+    }
+    // A single whitespace character separates the header from the raster.
+    if b, err := r.ReadByte(); err != nil || !(b == ' ' || b == '\t' || b == '\n' || b == '\r') {
+      return nil, ErrHeader
+    }
+  }
+
+  if h.width <= 0 || h.height <= 0 || h.maxval <= 0 || h.maxval > 65535 {
+    return nil, ErrHeader
+  }
+  if int64(h.width)*int64(h.height)*int64(h.depth) > MaxSamples {
+    return nil, ErrTooLarge
+  }
+  return h, nil
+}
+
+// readPAMHeader reads the keyword lines of a PAM header up to ENDHDR.
+func readPAMHeader(r reader, h *header) error {
+  for {
+    key, err := r.token()
+    if err != nil {
+      return ErrHeader
+    }
+    switch key {
+    case "WIDTH":
+      h.width, err = r.int()
+    case "HEIGHT":
+      h.height, err = r.int()
+    case "DEPTH":
+      h.depth, err = r.int()
+    case "MAXVAL":
+      h.maxval, err = r.int()
+    case "TUPLTYPE":
+      var line string
+      line, err = r.ReadString('\n')
+      h.tupltype = strings.TrimSpace(line)
+    case "ENDHDR":
+      if b, err := r.ReadByte(); err != nil || b != '\n' {
+        return ErrHeader
+      }
+      if h.depth < 1 || h.depth > 4 {
+        return fmt.Errorf("netpbm: unsupported PAM depth %d", h.depth)
+      }
+      return nil
+    default:
+      return ErrHeader
+    }
+    if err != nil {
+      return ErrHeader
+    }
+  }
+}
+
+// DecodeConfig returns the dimensions and color model of a Netpbm image
+// without reading its raster.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+  h, err := readHeader(reader{bufio.NewReader(r)})
+  if err != nil {
+    return image.Config{}, err
+  }
+  return image.Config{ColorModel: h.model(), Width: h.width, Height: h.height}, nil
+}
+
+// Decode reads a Netpbm image. Gray images decode to *image.Gray or
+// *image.Gray16, RGB images to *image.RGBA or *image.RGBA64, and PAM images
+// with alpha to *image.NRGBA or *image.NRGBA64, depending on whether maxval
+// exceeds 255. Samples are scaled to the full range of the result.
+func Decode(r io.Reader) (image.Image, error) {
+  h, samples, err := readSamples(r)
+  if err != nil {
+    return nil, err
+  }
+  return toImage(h, samples), nil
+}
+
+// Samples holds the unscaled raster of a Netpbm image: Depth samples per
+// pixel in row order, each at most Maxval. Data that is not an image, such as
+// a label map, should be read this way rather than through Decode.
+type Samples struct {
+  Width, Height int
+  Depth         int
+  Maxval        int
+  Pix           []uint16
+}
+
+func ReadSamples(r io.Reader) (*Samples, error) {
+  h, samples, err := readSamples(r)
+  if err != nil {
+    return nil, err
+  }
+  return &Samples{h.width, h.height, h.depth, h.maxval, samples}, nil
+}
+
+func readSamples(r io.Reader) (*header, []uint16, error) {
+  br := reader{bufio.NewReader(r)}
+  h, err := readHeader(br)
+  if err != nil {
+    return nil, nil, err
+  }
+
+  samples := make([]uint16, h.width*h.height*h.depth)
+  if h.plain() {
+    for i := range samples {
+      v, err := br.int()
+      if err != nil {
+        return nil, nil, io.ErrUnexpectedEOF
+      }
+      if v > h.maxval {
+        return nil, nil, ErrSample
+      }
+      samples[i] = uint16(v)
+    }
+  } else if err := readRaw(br, samples, h.maxval); err != nil {
+    return nil, nil, err
+  }
+  return h, samples, nil
+}
+
+func readRaw(r io.Reader, samples []uint16, maxval int) error {
+  size := 1
+  if maxval > 255 {
+    size = 2
+  }
+  buf := make([]byte, 4096*size)
+  for len(samples) > 0 {
+    n := len(buf) / size
+    if n > len(samples) {
+      n = len(samples)
+    }
+    if _, err := io.ReadFull(r, buf[:n*size]); err != nil {
+      if err == io.EOF {
+        err = io.ErrUnexpectedEOF
+      }
+      return err
+    }
+    for i := 0; i < n; i++ {
+      var v uint16
+      if size == 1 {
+        v = uint16(buf[i])
+      } else {
+        v = uint16(buf[2*i])<<8 | uint16(buf[2*i+1])
+      }
+      if int(v) > maxval {
+        return ErrSample
+      }
+      samples[i] = v
+    }
+    samples = samples[n:]
+  }
+  return nil
+}
+
+func toImage(h *header, samples []uint16) image.Image {
+  rect := image.Rect(0, 0, h.width, h.height)
+  deep := h.maxval > 255
+  scale := func(v uint16) uint16 {
+    if deep {
+      if h.maxval == 65535 {
+        return v
+      }
+      return uint16((uint32(v)*65535 + uint32(h.maxval)/2) / uint32(h.maxval))
+    }
+    if h.maxval == 255 {
+      return v
+    }
+    return uint16((uint32(v)*255 + uint32(h.maxval)/2) / uint32(h.maxval))
+  }
+  put := func(pix []uint8, i int, v uint16) int {
+    if deep {
+      pix[i], pix[i+1] = uint8(v>>8), uint8(v)
+      return i + 2
+    }
+    pix[i] = uint8(v)
+    return i + 1
+  }
+
+  var pix []uint8
+  var img image.Image
+  switch h.depth {
+  case 1:
+    if deep {
+      m := image.NewGray16(rect)
+      pix, img = m.Pix, m
+    } else {
+      m := image.NewGray(rect)
+      pix, img = m.Pix, m
+    }
+  case 3:
+    if deep {
+      m := image.NewRGBA64(rect)
+      pix, img = m.Pix, m
+    } else {
+      m := image.NewRGBA(rect)
+      pix, img = m.Pix, m
+    }
+  default:
+    if deep {
+      m := image.NewNRGBA64(rect)
+      pix, img = m.Pix, m
+    } else {
+      m := image.NewNRGBA(rect)
+      pix, img = m.Pix, m
+    }
+  }
+
+  full := uint16(255)
+  if deep {
+    full = 65535
+  }
+  i := 0
+  for s := 0; s < len(samples); s += h.depth {
+    switch h.depth {
+    case 1:
+      i = put(pix, i, scale(samples[s]))
+    case 2:
+      g := scale(samples[s])
+      i = put(pix, i, g)
+      i = put(pix, i, g)
+      i = put(pix, i, g)
+      i = put(pix, i, scale(samples[s+1]))
+    case 3:
+      i = put(pix, i, scale(samples[s]))
+      i = put(pix, i, scale(samples[s+1]))
+      i = put(pix, i, scale(samples[s+2]))
+      i = put(pix, i, full)
+    case 4:
+      i = put(pix, i, scale(samples[s]))
+      i = put(pix, i, scale(samples[s+1]))
+      i = put(pix, i, scale(samples[s+2]))
+      i = put(pix, i, scale(samples[s+3]))
+    }
+  }
+  return img
+}
@@ -0,0 +1,169 @@
+package netpbm
+
+import (
+  "bufio"
+  "fmt"
+  "image"
+  "image/color"
+  "io"
+  "strconv"
+)
+
+type Format int
+
+const (
+  // Auto writes PGM for *image.Gray and *image.Gray16 and PPM otherwise.
+  Auto Format = iota
+  PGM
+  PPM
+  // PAM keeps the alpha channel of images that are not opaque.
+  PAM
+)
+
+// Options controls Encode. A nil *Options writes raw PGM or PPM at the bit
+// depth of the image.
+type Options struct {
+  Format Format
+  // Plain writes the ASCII P2 and P3 variants. PAM has none.
+  Plain bool
+  // Maxval is the largest sample value, up to 65535. Zero means 65535 for
+  // images with 16-bit channels and 255 otherwise.
+  Maxval int
+}
+
+// Encode writes m in a Netpbm format. PPM and PGM have no alpha channel, so
+// translucent images are written as if composited over black.
+func Encode(w io.Writer, m image.Image, o *Options) error {
+  var opts Options
+  if o != nil {
+    opts = *o
+  }
+  gray, deep := false, false
+  switch m.(type) {
+  case *image.Gray:
+    gray = true
+  case *image.Gray16:
+    gray, deep = true, true
+  case *image.RGBA64, *image.NRGBA64:
+    deep = true
+  }
+  if opts.Format == Auto {
+    opts.Format = PPM
+    if gray {
+      opts.Format = PGM
+    }
+  }
+  if opts.Maxval == 0 {
+    opts.Maxval = 255
+    if deep {
+      opts.Maxval = 65535
+    }
+  }
+  if opts.Maxval < 1 || opts.Maxval > 65535 {
+    return fmt.Errorf("netpbm: invalid maxval %d", opts.Maxval)
+  }
+  if opts.Plain && opts.Format == PAM {
+    return fmt.Errorf("netpbm: PAM has no plain variant")
+  }
+
+  b := m.Bounds()
+  var depth int
+  bw := bufio.NewWriter(w)
+  switch opts.Format {
+  case PGM:
+    depth = 1
+    fmt.Fprintf(bw, "%s\n%d %d\n%d\n", pick(opts.Plain, "P2", "P5"), b.Dx(), b.Dy(), opts.Maxval)
+  case PPM:
+    depth = 3
+    fmt.Fprintf(bw, "%s\n%d %d\n%d\n", pick(opts.Plain, "P3", "P6"), b.Dx(), b.Dy(), opts.Maxval)
+  case PAM:
+    tupltype := "RGB"
+    depth = 3
+    if gray {
+      tupltype, depth = "GRAYSCALE", 1
+    }
+    if op, ok := m.(interface{ Opaque() bool }); !ok || !op.Opaque() {
+      tupltype += "_ALPHA"
+      depth++
+    }
+    fmt.Fprintf(bw, "P7\nWIDTH %d\nHEIGHT %d\nDEPTH %d\nMAXVAL %d\nTUPLTYPE %s\nENDHDR\n",
+      b.Dx(), b.Dy(), depth, opts.Maxval, tupltype)
+  default:
+    return fmt.Errorf("netpbm: unknown format %d", opts.Format)
+  }
+
+  sw := sampleWriter{w: bw, plain: opts.Plain, deep: opts.Maxval > 255, maxval: uint32(opts.Maxval)}
+  alpha := opts.Format == PAM && (depth == 2 || depth == 4)
+  for y := b.Min.Y; y < b.Max.Y; y++ {
+    for x := b.Min.X; x < b.Max.X; x++ {
+      c := m.At(x, y)
+      var r, g, bl, a uint32
+      if alpha {
+        n := color.NRGBA64Model.Convert(c).(color.NRGBA64)
+        r, g, bl, a = uint32(n.R), uint32(n.G), uint32(n.B), uint32(n.A)
+      } else {
+        r, g, bl, _ = c.RGBA()
+      }
+      if depth <= 2 {
+        sw.sample(uint32(color.Gray16Model.Convert(color.RGBA64{uint16(r), uint16(g), uint16(bl), 0xffff}).(color.Gray16).Y))
+      } else {
+        sw.sample(r)
+        sw.sample(g)
+        sw.sample(bl)
+      }
+      if alpha {
+        sw.sample(a)
+      }
+    }
+    if opts.Plain {
+      sw.newline()
+    }
+  }
+  return bw.Flush()
+}
+
+func pick(plain bool, p, raw string) string {
+  if plain {
+    return p
+  }
+  return raw
+}
+
+// sampleWriter scales 16-bit samples to maxval and writes them.
+type sampleWriter struct {
+  w      *bufio.Writer
+  plain  bool
+  deep   bool
+  maxval uint32
+  line   int
+}
+
+func (sw *sampleWriter) sample(v uint32) {
+  v = (v*sw.maxval + 32767) / 65535
+  switch {
+  case sw.plain:
+    s := strconv.Itoa(int(v))
+    // Plain format lines should not exceed 70 characters.
+    if sw.line > 0 && sw.line+1+len(s) > 70 {
+      sw.newline()
+    }
+    if sw.line > 0 {
+      sw.w.WriteByte(' ')
+      sw.line++
+    }
+    sw.w.WriteString(s)
+    sw.line += len(s)
+  case sw.deep:
+    sw.w.WriteByte(uint8(v >> 8))
+    sw.w.WriteByte(uint8(v))
+  default:
+    sw.w.WriteByte(uint8(v))
+  }
+}
+
+func (sw *sampleWriter) newline() {
+  if sw.line > 0 {
+    sw.w.WriteByte('\n')
+  }
+  sw.line = 0
+}
@@ -0,0 +1,147 @@
+package slic
+
+import "image"
+
+// Polygon is the outline of a superpixel traced along pixel edges. Vertices
+// are pixel corners in label map coordinates, so the pixel at (x, y) is the
+// square from (x, y) to (x+1, y+1). Rings are closed implicitly and have no
+// collinear vertices.
+//
+// Outer rings run clockwise on screen (positive area with y pointing down)
+// and holes run the other way. A superpixel normally has one outer ring,
+// which comes first.
+type Polygon struct {
+  Label int
+  Rings [][]image.Point
+}
+
+// Polygons traces the outline of every labeled superpixel, indexed by label.
+func (slic *SLIC) Polygons() []Polygon {
+  size := slic.image.Bounds().Size()
+  return tracePolygons(slic.Labels, size.X, size.Y, slic.labelCount)
+}
+
+type edge struct {
+  from, to image.Point
+}
+
+func tracePolygons(labels []int, width, height, count int) []Polygon {
+  at := func(x, y int) int {
+    if x < 0 || x >= width || y < 0 || y >= height {
+      return -1
+    }
+    return labels[y*width+x]
+  }
+
+  // Collect the boundary edges of every label, directed so that the label
+  // lies on their right.
+  edges := make([][]edge, count)
+  for y := 0; y < height; y++ {
+    for x := 0; x < width; x++ {
+      l := labels[y*width+x]
+      if l < 0 || l >= count {
+        continue
+      }
+      if at(x, y-1) != l {
+        edges[l] = append(edges[l], edge{image.Pt(x, y), image.Pt(x+1, y)})
+      }
+      if at(x+1, y) != l {
+        edges[l] = append(edges[l], edge{image.Pt(x+1, y), image.Pt(x+1, y+1)})
+      }
+      if at(x, y+1) != l {
+        edges[l] = append(edges[l], edge{image.Pt(x+1, y+1), image.Pt(x, y+1)})
+      }
+      if at(x-1, y) != l {
+        edges[l] = append(edges[l], edge{image.Pt(x, y+1), image.Pt(x, y)})
+      }
+    }
+  }
+
+  polygons := make([]Polygon, count)
+  for l := range polygons {
+    polygons[l] = Polygon{Label: l, Rings: linkRings(edges[l])}
+  }
+  return polygons
+}
+
+// linkRings joins directed edges into closed rings. Where a vertex has two
+// ways out, which happens when a region touches itself diagonally, the ring
+// turns right so that it stays on the same side of the pinch.
+func linkRings(edges []edge) [][]image.Point {
+  out := make(map[image.Point][]int, len(edges))
+  for i, e := range edges {
+    out[e.from] = append(out[e.from], i)
+  }
+  used := make([]bool, len(edges))
+
+  var rings [][]image.Point
+  for start := range edges {
+    if used[start] {
+      continue
+    }
+    var ring []image.Point
+    i := start
+    for !used[i] {
+      used[i] = true
+      ring = append(ring, edges[i].from)
+      next := -1
+      for _, j := range out[edges[i].to] {
+        if used[j] && j != start {
+          continue
+        }
+        if next < 0 || turn(edges[i], edges[j]) > turn(edges[i], edges[next]) {
+          next = j
+        }
+      }
+      if next < 0 {
+        break
+      }
+      i = next
+    }
+    rings = append(rings, simplify(ring))
+  }
+
+  // Put the largest outer ring first.
+  best := 0
+  for i, r := range rings {
+    if area(r) > area(rings[best]) {
+      best = i
+    }
+  }
+  if len(rings) > 0 {
+    rings[0], rings[best] = rings[best], rings[0]
+  }
+  return rings
+}
+
+// turn is positive when b turns right from a, zero when it goes straight and
+// negative when it turns left, in screen orientation.
+func turn(a, b edge) int {
+  d1 := a.to.Sub(a.from)
+  d2 := b.to.Sub(b.from)
+  return d1.X*d2.Y - d1.Y*d2.X
+}
+
+// simplify drops the vertices in the middle of straight runs.
+func simplify(ring []image.Point) []image.Point {
+  n := len(ring)
+  out := ring[:0:0]
+  for i, p := range ring {
+    prev, next := ring[(i+n-1)%n], ring[(i+1)%n]
+    d1, d2 := p.Sub(prev), next.Sub(p)
+    if d1.X*d2.Y-d1.Y*d2.X != 0 {
+      out = append(out, p)
+    }
+  }
+  return out
+}
+
+// area returns twice the signed area of ring, positive for outer rings.
+func area(ring []image.Point) int {
+  a := 0
+  for i, p := range ring {
+    q := ring[(i+1)%len(ring)]
+    a += p.X*q.Y - q.X*p.Y
+  }
+  return a
+}
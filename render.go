@@ -0,0 +1,125 @@
+package slic
+
+import (
+  "image"
+  "image/color"
+  "image/draw"
+  "math/rand"
+  "sort"
+)
+
+// MeanColorImage renders every superpixel filled with its mean color. Like
+// every render, the image has the bounds of the source image.
+func (slic *SLIC) MeanColorImage() *image.RGBA {
+  lvec, avec, bvec := slic.AverageColors()
+  return slic.fillLabels(func(label int) color.RGBA {
+    R, G, B := slic.space.ToRGB(lvec[label], avec[label], bvec[label])
+    return color.RGBA{R, G, B, 255}
+  })
+}
+
+// MedianColorImage renders every superpixel filled with the per-channel median
+// of its Lab colors, which is less affected by outlying pixels than the mean.
+func (slic *SLIC) MedianColorImage() *image.RGBA {
+  lvec, avec, bvec := slic.MedianColors()
+  return slic.fillLabels(func(label int) color.RGBA {
+    R, G, B := slic.space.ToRGB(lvec[label], avec[label], bvec[label])
+    return color.RGBA{R, G, B, 255}
+  })
+}
+
+// RandomColorImage renders every superpixel filled with a random color. The
+// palette depends only on seed, so renders are repeatable.
+func (slic *SLIC) RandomColorImage(seed int64) *image.RGBA {
+  rng := rand.New(rand.NewSource(seed))
+  palette := make([]color.RGBA, slic.labelCount)
+  for i := range palette {
+    palette[i] = color.RGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 255}
+  }
+  return slic.fillLabels(func(label int) color.RGBA {
+    return palette[label]
+  })
+}
+
+// CentroidDots returns a copy of img, in img's coordinate space, with a
+// square dot of the given radius drawn at every superpixel centroid.
+func (slic *SLIC) CentroidDots(img image.Image, radius int, c color.Color) *image.RGBA {
+  b := img.Bounds()
+  canvas := image.NewRGBA(b)
+  draw.Draw(canvas, b, img, b.Min, draw.Src)
+
+  for _, s := range slic.Superpixels {
+    x := b.Min.X + int(s.X+0.5)
+    y := b.Min.Y + int(s.Y+0.5)
+    dot := image.Rect(x-radius, y-radius, x+radius+1, y+radius+1).Intersect(b)
+    draw.Draw(canvas, dot, image.NewUniform(c), image.Point{}, draw.Over)
+  }
+
+  return canvas
+}
+
+// MedianColors returns the per-channel median color of every label, in the
+// color space SLIC clustered in.
+func (slic *SLIC) MedianColors() (lvec, avec, bvec []float64) {
+  lvec = make([]float64, slic.labelCount)
+  avec = make([]float64, slic.labelCount)
+  bvec = make([]float64, slic.labelCount)
+  lsamples := make([][]float64, slic.labelCount)
+  asamples := make([][]float64, slic.labelCount)
+  bsamples := make([][]float64, slic.labelCount)
+
+  size := slic.image.Bounds().Size()
+  width, height := size.X, size.Y
+
+  for y := 0; y < height; y++ {
+    for x := 0; x < width; x++ {
+      label := slic.Labels[y*width+x]
+      if label < 0 {
+        continue
+      }
+      c := slic.image.LabAt(x, y)
+      lsamples[label] = append(lsamples[label], c.L)
+      asamples[label] = append(asamples[label], c.A)
+      bsamples[label] = append(bsamples[label], c.B)
+    }
+  }
+
+  for i := 0; i < slic.labelCount; i++ {
+    lvec[i] = median(lsamples[i])
+    avec[i] = median(asamples[i])
+    bvec[i] = median(bsamples[i])
+  }
+
+  return
+}
+
+func (slic *SLIC) fillLabels(colorOf func(label int) color.RGBA) *image.RGBA {
+  size := slic.image.Bounds().Size()
+  width, height := size.X, size.Y
+  b := slic.bounds
+  out := image.NewRGBA(b)
+
+  for y := 0; y < height; y++ {
+    for x := 0; x < width; x++ {
+      label := slic.Labels[y*width+x]
+      if label == -1 {
+        continue
+      }
+      out.SetRGBA(b.Min.X+x, b.Min.Y+y, colorOf(label))
+    }
+  }
+
+  return out
+}
+
+func median(samples []float64) float64 {
+  n := len(samples)
+  if n == 0 {
+    return 0
+  }
+  sort.Float64s(samples)
+  if n%2 == 1 {
+    return samples[n/2]
+  }
+  return (samples[n/2-1] + samples[n/2]) / 2
+}
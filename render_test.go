@@ -0,0 +1,104 @@
+package slic
+
+import (
+  "image"
+  "image/color"
+  "math"
+  "testing"
+
+  . "github.com/franela/goblin"
+  "github.com/kurige/SLIC/colorspace"
+)
+
+// redAndBlue is halves over red in label 0 with one white outlier at the top
+// left, and blue in label 1.
+func redAndBlue(origin image.Point) (*SLIC, *image.RGBA) {
+  return halves(origin, func(x, y int) color.RGBA {
+    switch {
+    case x == 0 && y == 0:
+      return color.RGBA{255, 255, 255, 255}
+    case x < 3:
+      return color.RGBA{255, 0, 0, 255}
+    }
+    return color.RGBA{0, 0, 255, 255}
+  })
+}
+
+func near(c color.RGBA, r, g, b uint8) bool {
+  d := func(u, v uint8) bool { return math.Abs(float64(u)-float64(v)) <= 1 }
+  return d(c.R, r) && d(c.G, g) && d(c.B, b) && c.A == 255
+}
+
+func TestRender(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("Renderers", func() {
+    g.It("Take the per-channel median of each superpixel", func() {
+      s, _ := redAndBlue(image.Point{})
+      lvec, avec, bvec := s.MedianColors()
+      red := s.image.LabAt(1, 0)
+      g.Assert([]float64{lvec[0], avec[0], bvec[0]}).Equal([]float64{red.L, red.A, red.B})
+      mean, _, _ := s.AverageColors()
+      g.Assert(mean[0] > red.L).IsTrue()
+    })
+    g.It("Average the middle two samples of an even count", func() {
+      g.Assert(median([]float64{4, 1, 3, 2})).Equal(2.5)
+      g.Assert(median([]float64{3, 1, 2})).Equal(2.0)
+      g.Assert(median(nil)).Equal(0.0)
+    })
+    g.It("Fill superpixels with their median color, ignoring outliers", func() {
+      s, _ := redAndBlue(image.Point{})
+      out := s.MedianColorImage()
+      g.Assert(near(out.RGBAAt(0, 0), 255, 0, 0)).IsTrue()
+      g.Assert(near(out.RGBAAt(5, 3), 0, 0, 255)).IsTrue()
+      mean := s.MeanColorImage()
+      g.Assert(near(mean.RGBAAt(0, 0), 255, 0, 0)).IsFalse()
+    })
+    g.It("Fill superpixels with repeatable random colors", func() {
+      s, _ := redAndBlue(image.Point{})
+      a, b := s.RandomColorImage(7), s.RandomColorImage(7)
+      g.Assert(a.Pix).Equal(b.Pix)
+      g.Assert(a.RGBAAt(0, 0)).Equal(a.RGBAAt(2, 3))
+      g.Assert(a.RGBAAt(0, 0) != a.RGBAAt(5, 0)).IsTrue()
+    })
+    g.It("Fill images with the source's bounds", func() {
+      s, img := redAndBlue(image.Pt(10, 20))
+      for _, out := range []*image.RGBA{s.MeanColorImage(), s.MedianColorImage(), s.RandomColorImage(1)} {
+        g.Assert(out.Bounds()).Equal(img.Bounds())
+        g.Assert(out.RGBAAt(10, 20)).Equal(out.RGBAAt(12, 23))
+        g.Assert(out.RGBAAt(10, 20) != out.RGBAAt(13, 20)).IsTrue()
+      }
+      median := s.MedianColorImage()
+      g.Assert(near(median.RGBAAt(10, 20), 255, 0, 0)).IsTrue()
+      g.Assert(near(median.RGBAAt(15, 23), 0, 0, 255)).IsTrue()
+      for _, space := range []colorspace.Space{nil, colorspace.OKLabSpace} {
+        sub := testImage(40, 30).SubImage(image.Rect(10, 5, 40, 30))
+        sl := MakeSlicWithOptions(sub, 20, 100, Options{Space: space})
+        sl.Run(2)
+        g.Assert(sl.MeanColorImage().Bounds()).Equal(sub.Bounds())
+      }
+    })
+    g.It("Dot centroids in the source's coordinates", func() {
+      s, img := redAndBlue(image.Pt(10, 20))
+      green := color.RGBA{0, 255, 0, 255}
+      out := s.CentroidDots(img, 0, green)
+      g.Assert(out.Bounds()).Equal(img.Bounds())
+      // Centroids are (1, 1.5) and (4, 1.5), rounded to (1, 2) and (4, 2).
+      dots := 0
+      for y := 20; y < 24; y++ {
+        for x := 10; x < 16; x++ {
+          if out.RGBAAt(x, y) == green {
+            dots++
+          }
+        }
+      }
+      g.Assert(dots).Equal(2)
+      g.Assert(out.RGBAAt(11, 22)).Equal(green)
+      g.Assert(out.RGBAAt(14, 22)).Equal(green)
+
+      big := s.CentroidDots(img, 1, green)
+      g.Assert(big.RGBAAt(10, 21)).Equal(green)
+      g.Assert(big.RGBAAt(12, 23)).Equal(green)
+      g.Assert(big.RGBAAt(13, 20)).Equal(img.RGBAAt(13, 20))
+    })
+  })
+}
@@ -0,0 +1,59 @@
+package slic
+
+type Seeding int
+
+const (
+  // SeedGrid places the initial centers on a regular square grid.
+  SeedGrid Seeding = iota
+  // SeedPerturbed moves every grid center to the lowest gradient position in
+  // its 3x3 neighbourhood, so that seeds do not start on an edge or a noisy
+  // pixel.
+  SeedPerturbed
+  // SeedHex shifts every other row of the grid by half a step, giving a
+  // hexagonal layout in which each center is about equally far from its six
+  // nearest neighbours.
+  SeedHex
+)
+
+func (slic *SLIC) perturbSeeds() {
+  size := slic.image.Bounds().Size()
+  width, height := size.X, size.Y
+
+  for _, s := range slic.Superpixels {
+    ox, oy := int(s.X), int(s.Y)
+    bestx, besty := ox, oy
+    best := slic.gradient(ox, oy, width, height)
+    for dy := -1; dy <= 1; dy++ {
+      for dx := -1; dx <= 1; dx++ {
+        x, y := ox+dx, oy+dy
+        if x < 0 || x >= width || y < 0 || y >= height {
+          continue
+        }
+        if g := slic.gradient(x, y, width, height); g < best {
+          best, bestx, besty = g, x, y
+        }
+      }
+    }
+    c := slic.image.LabAt(bestx, besty)
+    s.L, s.A, s.B = c.L, c.A, c.B
+    s.X, s.Y = float64(bestx), float64(besty)
+  }
+}
+
+// gradient returns the squared color gradient magnitude at (x, y), using
+// central differences clamped at the image border.
+func (slic *SLIC) gradient(x, y, width, height int) float64 {
+  clamp := func(v, n int) int {
+    if v < 0 {
+      return 0
+    }
+    if v >= n {
+      return n - 1
+    }
+    return v
+  }
+  l, r := slic.image.LabAt(clamp(x-1, width), y), slic.image.LabAt(clamp(x+1, width), y)
+  u, d := slic.image.LabAt(x, clamp(y-1, height)), slic.image.LabAt(x, clamp(y+1, height))
+  return (r.L-l.L)*(r.L-l.L) + (r.A-l.A)*(r.A-l.A) + (r.B-l.B)*(r.B-l.B) +
+    (d.L-u.L)*(d.L-u.L) + (d.A-u.A)*(d.A-u.A) + (d.B-u.B)*(d.B-u.B)
+}
@@ -1,51 +1,175 @@
 package slic
 
 import (
+  "context"
+  "errors"
   "image"
   "image/color"
-  "image/draw"
   "math"
+  "time"
 
+  "github.com/kurige/SLIC/colorspace"
   "github.com/kurige/SLIC/lab"
 )
 
 /*
  * TODO:
  * - More accurate LAB color diffing
- * - "Upgrade" to SLICO (or make it an option)
  * - Use all avaialble cores
- * - Perturb superpixels during seeding
- * - Support hexgrid seeding(?)
  */
 
+var (
+  ErrLabelCount = errors.New("slic: label map size does not match image size")
+  ErrNoLabels   = errors.New("slic: label map has no labeled pixels")
+)
+
 type SuperPixel struct {
   label   int
   L, A, B float64
   X, Y    float64
+  // Count is the number of pixels labeled with the superpixel.
+  Count int
+  // Seed is the index into Seeds of the center this superpixel grew from.
+  // Connectivity enforcement can split one cluster into several superpixels,
+  // which then share a seed. It is -1 for a region no center reached.
+  Seed int
 }
 
 type SLIC struct {
-  image       lab.Image
+  image       lab.Reader
+  bounds      image.Rectangle // of the source image, for rendering
+  space       colorspace.Space
   compactness float64
   step        int
   distvec     []float64
   Superpixels []*SuperPixel
+  seeds       []image.Point
   XStrips     int
   YStrips     int
 
   Labels     []int
   labelCount int
+
+  // Iterations is the number of iterations the last Run performed.
+  Iterations int
+  Timings    Timings
+
+  distance    Distance
+  convergence float64
+  maxlab      []float64
+  distlab     []float64
+
+  connectivity Connectivity
+  minsize      int
+  minsizeRatio float64
+  merge        Merge
 }
 
 func SuperPixelSizeForCount(width, height, count int) int {
   return int(0.5 + float64(width*height)/float64(count))
 }
 
+// Options holds the less common SLIC settings. The zero Options is what
+// MakeSlic uses.
+type Options struct {
+  // Illuminant and Observer select the white point that Lab colors are
+  // relative to. The default is D65 with the 2° observer, as for sRGB.
+  Illuminant lab.Illuminant
+  Observer   lab.Observer
+  // Profile is the RGB color space of the input image. Defaults to sRGB.
+  Profile *lab.Profile
+  // Space is the color space pixels are clustered in. Defaults to CIELAB
+  // under the settings above, which do not apply to other spaces. Colors
+  // reported by AverageColors and MedianColors are in this space.
+  Space colorspace.Space
+  // Seeding chooses where the initial cluster centers are placed.
+  Seeding Seeding
+  // Distance chooses how color and spatial distance are combined.
+  Distance Distance
+  // Convergence, when positive, ends Run early once the centers move by
+  // less than this many pixels on average in an iteration. The iteration
+  // count passed to Run is then an upper bound.
+  Convergence float64
+  // Connectivity chooses whether pixels touching only at a corner belong to
+  // the same superpixel after Run.
+  Connectivity Connectivity
+  // MinSize is the smallest superpixel, in pixels, that Run keeps; smaller
+  // fragments are merged into a neighbour. A negative MinSize keeps every
+  // fragment. When zero, MinSizeRatio applies instead.
+  MinSize int
+  // MinSizeRatio sets the minimum size relative to the expected superpixel
+  // area: fragments no larger than this fraction of it are merged. Defaults
+  // to a quarter.
+  MinSizeRatio float64
+  // Merge chooses which neighbour absorbs a fragment below the minimum size.
+  Merge Merge
+}
+
+// Timings records how long each phase of a segmentation took. Assignment and
+// Update are summed over all iterations of the last Run.
+type Timings struct {
+  // Conversion is the color conversion in MakeSlic. It is zero when the
+  // image was already converted.
+  Conversion   time.Duration
+  Assignment   time.Duration
+  Update       time.Duration
+  Connectivity time.Duration
+}
+
+type Distance int
+
+const (
+  // DistanceSLIC weighs spatial distance against color distance by the
+  // fixed compactness.
+  DistanceSLIC Distance = iota
+  // DistanceSLICO normalizes color distance by the largest one seen in each
+  // superpixel in the previous iteration, as in SLICO (zero parameter SLIC).
+  // Superpixels come out regularly shaped in both flat and textured areas
+  // and compactness is ignored.
+  DistanceSLICO
+)
+
 func MakeSlic(image image.Image, compactness float64, supsz int) *SLIC {
+  return MakeSlicWithOptions(image, compactness, supsz, Options{})
+}
+
+func MakeSlicWithOptions(image image.Image, compactness float64, supsz int, opts Options) *SLIC {
+  start := time.Now()
+  if opts.Space != nil {
+    img := features{colorspace.ImageToSpace(image, opts.Space)}
+    conversion := time.Since(start)
+    slic := MakeSlicFromLabWithOptions(img, compactness, supsz, opts)
+    slic.space = opts.Space
+    slic.bounds = image.Bounds()
+    slic.Timings.Conversion = conversion
+    return slic
+  }
+  profile := opts.Profile
+  if profile == nil {
+    profile = lab.SRGB
+  }
+  converter := lab.NewProfileConverter(profile, opts.Illuminant.WhitePoint(opts.Observer))
+  img := converter.ImageToLab(image)
+  conversion := time.Since(start)
+  slic := MakeSlicFromLabWithOptions(&img, compactness, supsz, opts)
+  slic.space = colorspace.NewLabSpace(converter)
+  slic.bounds = image.Bounds()
+  slic.Timings.Conversion = conversion
+  return slic
+}
+
+// MakeSlicFromLab is MakeSlic for an image that is already in Lab, such as a
+// lab.Image32 when memory is tight. Colors are taken to be relative to D65.
+func MakeSlicFromLab(img lab.Reader, compactness float64, supsz int) *SLIC {
+  return MakeSlicFromLabWithOptions(img, compactness, supsz, Options{})
+}
+
+// MakeSlicFromLabWithOptions is MakeSlicFromLab with settings. The color
+// conversion settings in opts do not apply.
+func MakeSlicFromLabWithOptions(img lab.Reader, compactness float64, supsz int, opts Options) *SLIC {
   var (
-    w    = image.Bounds().Size().X
-    h    = image.Bounds().Size().Y
-    sz   = w * h
+    w    = img.Bounds().Size().X
+    h    = img.Bounds().Size().Y
     step = int(math.Sqrt(float64(supsz)) + 0.5)
   )
   x_strips := int(0.5 + float64(w)/float64(step))
@@ -61,31 +185,19 @@ func MakeSlic(image image.Image, compactness float64, supsz int) *SLIC {
     y_err = h - step*y_strips
   }
 
-  labels := make([]int, sz)
-  for i := 0; i < sz; i++ {
-    labels[i] = -1
-  }
-  distvec := make([]float64, sz)
-
   // Overwrite user selected superpixel count if necessary.
   supsz = x_strips * y_strips
-  superpixels := make([]*SuperPixel, supsz)
-
-  img := lab.ImageToLab(image)
-
-  slic := &SLIC{
-    img,
-    compactness,
-    step,
-    distvec,
-    superpixels,
 
-    x_strips,
-    y_strips,
-
-    labels,
-    0,
-  }
+  slic := newSlic(img, compactness, step, supsz)
+  slic.distance = opts.Distance
+  slic.convergence = opts.Convergence
+  slic.connectivity = opts.Connectivity
+  slic.minsize = opts.MinSize
+  slic.minsizeRatio = opts.MinSizeRatio
+  slic.merge = opts.Merge
+  slic.XStrips = x_strips
+  slic.YStrips = y_strips
+  superpixels := slic.Superpixels
 
   x_err_per_strip := float64(x_err) / float64(x_strips)
   y_err_per_strip := float64(y_err) / float64(y_strips)
@@ -99,27 +211,193 @@ func MakeSlic(image image.Image, compactness float64, supsz int) *SLIC {
         xe    = x * int(x_err_per_strip)
         seedx = x*step + x_offset + xe
         seedy = y*step + y_offset + ye
-        c     = img.At(seedx, seedy).(lab.Color)
       )
-      superpixels[label] = &SuperPixel{label, c.L, c.A, c.B, float64(seedx), float64(seedy)}
+      if opts.Seeding == SeedHex && y%2 == 1 {
+        seedx += step / 2
+        if seedx >= w {
+          seedx = w - 1
+        }
+      }
+      c := slic.image.LabAt(seedx, seedy)
+      superpixels[label] = &SuperPixel{label: label, L: c.L, A: c.A, B: c.B, X: float64(seedx), Y: float64(seedy), Seed: label}
       label++
     }
   }
+  if opts.Seeding == SeedPerturbed {
+    slic.perturbSeeds()
+  }
+  slic.recordSeeds()
 
   return slic
 }
 
+// MakeSlicFromLabels creates a SLIC seeded from an existing segmentation of
+// image rather than from a regular grid. Labels are in row-major order and may
+// use any non-negative numbering; negative labels mark unlabeled pixels.
+// Superpixel centroids are derived from the label map, so Run refines the
+// given segmentation.
+func MakeSlicFromLabels(image image.Image, compactness float64, labels []int) (*SLIC, error) {
+  var (
+    w  = image.Bounds().Size().X
+    h  = image.Bounds().Size().Y
+    sz = w * h
+  )
+  if len(labels) != sz {
+    return nil, ErrLabelCount
+  }
+
+  // Renumber labels to 0..n-1 in order of first appearance.
+  renumber := make(map[int]int)
+  for _, l := range labels {
+    if _, ok := renumber[l]; !ok && l >= 0 {
+      renumber[l] = len(renumber)
+    }
+  }
+  supsz := len(renumber)
+  if supsz == 0 {
+    return nil, ErrNoLabels
+  }
+
+  step := int(math.Sqrt(float64(sz) / float64(supsz)))
+  if step < 1 {
+    step = 1
+  }
+
+  img := lab.ImageToLab(image)
+  slic := newSlic(&img, compactness, step, supsz)
+  slic.labelCount = supsz
+  for i, l := range labels {
+    if l >= 0 {
+      slic.Labels[i] = renumber[l]
+    }
+  }
+  slic.bounds = image.Bounds()
+  slic.recalculateCentroids()
+  slic.recordSeeds()
+
+  return slic, nil
+}
+
+func newSlic(img lab.Reader, compactness float64, step, supsz int) *SLIC {
+  bounds := img.Bounds()
+  if off := img.Bounds().Min; off != (image.Point{}) {
+    img = origin{img, off}
+  }
+  size := img.Bounds().Size()
+  sz := size.X * size.Y
+
+  labels := make([]int, sz)
+  for i := 0; i < sz; i++ {
+    labels[i] = -1
+  }
+
+  superpixels := make([]*SuperPixel, supsz)
+  for n := range superpixels {
+    superpixels[n] = &SuperPixel{label: n, Seed: n}
+  }
+
+  return &SLIC{
+    image:       img,
+    bounds:      bounds,
+    space:       colorspace.LabSpace,
+    compactness: compactness,
+    step:        step,
+    distvec:     make([]float64, sz),
+    Superpixels: superpixels,
+    Labels:      labels,
+  }
+}
+
+func (slic *SLIC) recordSeeds() {
+  slic.seeds = make([]image.Point, len(slic.Superpixels))
+  for n, s := range slic.Superpixels {
+    slic.seeds[n] = image.Pt(int(s.X+0.5), int(s.Y+0.5))
+  }
+}
+
+// origin moves an image's bounds to start at (0, 0), which the clustering
+// code assumes.
+type origin struct {
+  lab.Reader
+  min image.Point
+}
+
+func (o origin) Bounds() image.Rectangle {
+  return o.Reader.Bounds().Sub(o.min)
+}
+
+func (o origin) LabAt(x, y int) lab.Color {
+  return o.Reader.LabAt(x+o.min.X, y+o.min.Y)
+}
+
+func (o origin) At(x, y int) color.Color {
+  return o.LabAt(x, y)
+}
+
+// features presents an image in another color space as a lab.Reader, so its
+// coordinates are clustered as if they were L, a and b.
+type features struct {
+  *colorspace.Image
+}
+
+func (f features) LabAt(x, y int) lab.Color {
+  X, Y, Z := f.ChannelsAt(x, y)
+  return lab.Color{L: X, A: Y, B: Z}
+}
+
 func (slic *SLIC) Run(iterations int) {
+  slic.RunContext(context.Background(), iterations)
+}
+
+// RunContext is Run that stops early when ctx is done, returning ctx.Err().
+// The context is checked between iterations and while pixels are assigned,
+// and Labels are left in an unspecified state if it ends the run.
+func (slic *SLIC) RunContext(ctx context.Context, iterations int) error {
   if iterations <= 0 {
     iterations = 1
   }
+  if slic.distance == DistanceSLICO {
+    slic.maxlab = make([]float64, len(slic.Superpixels))
+    for n := range slic.maxlab {
+      slic.maxlab[n] = 10 * 10
+    }
+    slic.distlab = make([]float64, len(slic.distvec))
+  }
+  prevX := make([]float64, len(slic.Superpixels))
+  prevY := make([]float64, len(slic.Superpixels))
+  slic.Iterations = 0
+  slic.Timings.Assignment, slic.Timings.Update = 0, 0
   for i := 0; i < iterations; i++ {
+    for n, s := range slic.Superpixels {
+      prevX[n], prevY[n] = s.X, s.Y
+    }
+    start := time.Now()
     slic.resetDistances()
-    slic.labelPixels()
+    if err := slic.labelPixels(ctx); err != nil {
+      return err
+    }
+    if slic.distance == DistanceSLICO {
+      slic.updateMaxColorDistances()
+    }
+    slic.Timings.Assignment += time.Since(start)
+    start = time.Now()
     slic.recalculateCentroids()
+    slic.Timings.Update += time.Since(start)
+    slic.Iterations++
+
+    if slic.convergence > 0 {
+      var moved float64
+      for n, s := range slic.Superpixels {
+        moved += math.Hypot(s.X-prevX[n], s.Y-prevY[n])
+      }
+      if moved/float64(len(slic.Superpixels)) < slic.convergence {
+        break
+      }
+    }
   }
 
-  label_count, new_labels := slic.enforceLabelConnectivity()
+  start := time.Now()
+  label_count, new_labels, origins := slic.enforceLabelConnectivity()
   slic.labelCount = label_count
 
   size := slic.image.Bounds().Size()
@@ -127,6 +405,46 @@ func (slic *SLIC) Run(iterations int) {
   for i := 0; i < sz; i++ {
     slic.Labels[i] = new_labels[i]
   }
+  slic.relabel(origins)
+  slic.recalculateCentroids()
+  slic.Timings.Connectivity = time.Since(start)
+  return nil
+}
+
+// relabel replaces Superpixels with one per label connectivity enforcement
+// assigned, where origins[n] is the cluster that label n was cut from. Their
+// colors and centroids are left for recalculateCentroids.
+func (slic *SLIC) relabel(origins []int) {
+  superpixels := make([]*SuperPixel, len(origins))
+  for n, o := range origins {
+    seed := -1 // A region no center reached.
+    if o >= 0 {
+      seed = slic.Superpixels[o].Seed
+    }
+    superpixels[n] = &SuperPixel{label: n, Seed: seed}
+  }
+  slic.Superpixels = superpixels
+}
+
+// LabelCount returns the number of superpixels after Run, or in the label map
+// given to MakeSlicFromLabels before it. Labels run from 0 to
+// LabelCount()-1, numbered in raster order of each superpixel's first pixel,
+// and Superpixels[label] holds the mean color, centroid and pixel count of
+// superpixel label.
+func (slic *SLIC) LabelCount() int {
+  return slic.labelCount
+}
+
+// Seeds returns where each cluster center was placed before the first Run,
+// indexed by SuperPixel.Seed.
+func (slic *SLIC) Seeds() []image.Point {
+  return slic.seeds
+}
+
+// Space returns the color space pixels were clustered in, which is also the
+// space of the colors AverageColors and MedianColors return.
+func (slic *SLIC) Space() colorspace.Space {
+  return slic.space
 }
 
 func (slic *SLIC) resetDistances() {
@@ -135,15 +453,30 @@ func (slic *SLIC) resetDistances() {
   }
 }
 
-func (slic *SLIC) labelPixels() {
+func (slic *SLIC) labelPixels(ctx context.Context) error {
+  done := ctx.Done()
   for n := range slic.Superpixels {
+    if done != nil && n%64 == 0 {
+      select {
+      case <-done:
+        return ctx.Err()
+      default:
+      }
+    }
     slic.labelPixelsInSuperpixel(slic.Superpixels[n])
   }
+  return nil
 }
 
 func (slic *SLIC) labelPixelsInSuperpixel(s *SuperPixel) {
   fstep := float64(slic.step)
   invwt := 1.0 / ((fstep / slic.compactness) * (fstep / slic.compactness))
+  invxy := 1.0 / (fstep * fstep)
+  slico := slic.distance == DistanceSLICO
+  var maxlab float64
+  if slico {
+    maxlab = slic.maxlab[s.label]
+  }
 
   size := slic.image.Bounds().Size()
   width, height := size.X, size.Y
@@ -157,22 +490,43 @@ func (slic *SLIC) labelPixelsInSuperpixel(s *SuperPixel) {
 
   for y := y1; y < y2; y++ {
     for x := x1; x < x2; x++ {
-      c := slic.image.At(x, y).(lab.Color)
+      c := slic.image.LabAt(x, y)
       X, Y := float64(x), float64(y)
       var distc float64 = (c.L-supL)*(c.L-supL) + (c.A-supA)*(c.A-supA) + (c.B-supB)*(c.B-supB)
       var distxy float64 = (X-supX)*(X-supX) + (Y-supY)*(Y-supY)
 
-      dist := math.Sqrt(distc) + math.Sqrt(distxy*invwt)
+      var dist float64
+      if slico {
+        dist = distc/maxlab + distxy*invxy
+      } else {
+        dist = math.Sqrt(distc) + math.Sqrt(distxy*invwt)
+      }
 
       i := y*width + x
       if dist < slic.distvec[i] {
         slic.distvec[i] = dist
         slic.Labels[i] = s.label
+        if slico {
+          slic.distlab[i] = distc
+        }
       }
     }
   }
 }
 
+// updateMaxColorDistances sets the SLICO color normalization of every
+// superpixel to the largest color distance among its pixels.
+func (slic *SLIC) updateMaxColorDistances() {
+  for n := range slic.maxlab {
+    slic.maxlab[n] = 1
+  }
+  for i, label := range slic.Labels {
+    if label >= 0 && slic.distlab[i] > slic.maxlab[label] {
+      slic.maxlab[label] = slic.distlab[i]
+    }
+  }
+}
+
 func (slic *SLIC) AverageColors() (lvec, avec, bvec []float64) {
   lvec = make([]float64, slic.labelCount)
   avec = make([]float64, slic.labelCount)
@@ -186,7 +540,10 @@ func (slic *SLIC) AverageColors() (lvec, avec, bvec []float64) {
     for x := 0; x < width; x++ {
       i := y*width + x
       label := slic.Labels[i]
-      c := slic.image.At(x, y).(lab.Color)
+      if label < 0 {
+        continue
+      }
+      c := slic.image.LabAt(x, y)
       lvec[label] += c.L
       avec[label] += c.A
       bvec[label] += c.B
@@ -224,7 +581,7 @@ func (slic *SLIC) recalculateCentroids() {
       if label == -1 {
         continue
       }
-      c := slic.image.At(x, y).(lab.Color)
+      c := slic.image.LabAt(x, y)
       sigma_l[label] += c.L
       sigma_a[label] += c.A
       sigma_b[label] += c.B
@@ -235,11 +592,12 @@ func (slic *SLIC) recalculateCentroids() {
   }
 
   for n := 0; n < supsz; n++ {
+    superpixel := slic.Superpixels[n]
+    superpixel.Count = int(clustersize[n])
     if clustersize[n] <= 0 {
       clustersize[n] = 1.0
     }
 
-    superpixel := slic.Superpixels[n]
     superpixel.L = sigma_l[n] / clustersize[n]
     superpixel.A = sigma_a[n] / clustersize[n]
     superpixel.B = sigma_b[n] / clustersize[n]
@@ -248,15 +606,29 @@ func (slic *SLIC) recalculateCentroids() {
   }
 }
 
-func (slic *SLIC) enforceLabelConnectivity() (int, []int) {
+// enforceLabelConnectivity relabels every connected region of a cluster as
+// its own superpixel, in raster order of first pixel, and merges regions
+// smaller than the minimum size into a neighbouring superpixel as the merge
+// policy says. It returns the number of superpixels, the new labels and, for
+// each new label, the cluster it came from.
+func (slic *SLIC) enforceLabelConnectivity() (int, []int, []int) {
   size := slic.image.Bounds().Size()
   width, height := size.X, size.Y
   sz := width * height
   target_supsz := sz / (slic.step * slic.step)
+  if target_supsz < 1 {
+    target_supsz = 1
+  }
   SUPSZ := sz / target_supsz
 
-  dx4 := [...]int{-1, 0, 1, 0}
-  dy4 := [...]int{0, -1, 0, 1}
+  minsize := slic.minSize(SUPSZ)
+  if slic.merge == MergeClosestColor {
+    // Find every region first, so that fragments can choose among all
+    // their neighbours rather than only those already numbered.
+    minsize = 0
+  }
+
+  dx, dy := neighbours(slic.connectivity)
 
   xvec := make([]int, sz)
   yvec := make([]int, sz)
@@ -265,6 +637,7 @@ func (slic *SLIC) enforceLabelConnectivity() (int, []int) {
 
   label := 0
   nlabels := make([]int, sz)
+  var origins []int
 
   for i := 0; i < sz; i++ {
     nlabels[i] = -1
@@ -274,15 +647,19 @@ func (slic *SLIC) enforceLabelConnectivity() (int, []int) {
     for k := 0; k < width; k++ {
       if 0 > nlabels[oindex] {
         nlabels[oindex] = label
+        if label == len(origins) {
+          origins = append(origins, 0)
+        }
+        origins[label] = slic.Labels[oindex]
 
         // Start a new segment
         xvec[0] = k
         yvec[0] = j
 
         // Quickly find an adjacent label for use later if needed
-        for n := 0; n < 4; n++ {
-          x := xvec[0] + dx4[n]
-          y := yvec[0] + dy4[n]
+        for n := range dx {
+          x := xvec[0] + dx[n]
+          y := yvec[0] + dy[n]
           if (x >= 0 && x < width) && (y >= 0 && y < height) {
             nindex := y*width + x
             if nlabels[nindex] >= 0 {
@@ -293,9 +670,9 @@ func (slic *SLIC) enforceLabelConnectivity() (int, []int) {
 
         count := 1
         for c := 0; c < count; c++ {
-          for n := 0; n < 4; n++ {
-            x := xvec[c] + dx4[n]
-            y := yvec[c] + dy4[n]
+          for n := range dx {
+            x := xvec[c] + dx[n]
+            y := yvec[c] + dy[n]
 
             if (x >= 0 && x < width) && (y >= 0 && y < height) {
               nindex := y*width + x
@@ -312,7 +689,7 @@ func (slic *SLIC) enforceLabelConnectivity() (int, []int) {
 
         // If segment size is less than the limit, assign an adjacent label
         // found before, and decrement label count.
-        if count <= SUPSZ>>2 {
+        if count < minsize {
           for c := 0; c < count; c++ {
             ind := yvec[c]*width + xvec[c]
             nlabels[ind] = adjlabel
@@ -325,67 +702,9 @@ func (slic *SLIC) enforceLabelConnectivity() (int, []int) {
     }
   }
 
-  return label, nlabels
-}
-
-func (slic *SLIC) DrawEdgesToImage(img image.Image) image.Image {
-  // Create new RGBA image from source
-  b := img.Bounds()
-  canvas := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
-  draw.Draw(canvas, canvas.Bounds(), img, b.Min, draw.Src)
-
-  dx8 := []int{-1, -1, 0, 1, 1, 1, 0, -1}
-  dy8 := []int{0, -1, -1, -1, 0, 1, 1, 1}
-
-  size := slic.image.Bounds().Size()
-  width, height := size.X, size.Y
-  sz := width * height
-  contourx := make([]int, sz)
-  contoury := make([]int, sz)
-  istaken := make([]bool, sz)
-  mainindex := 0
-  cind := 0
-
-  black := color.RGBA{0, 0, 0, 254}
-  red := color.RGBA{254, 0, 0, 254}
-
-  for j := 0; j < height; j++ {
-    for k := 0; k < width; k++ {
-      if slic.Labels[mainindex] == -1 {
-        canvas.Set(k, j, red)
-        mainindex++
-        continue
-      }
-
-      np := 0
-      for i := 0; i < 8; i++ {
-        x := k + dx8[i]
-        y := j + dy8[i]
-
-        if (x >= 0 && x < width) && (y >= 0 && y < height) {
-          index := y*width + x
-          if !istaken[index] {
-            if slic.Labels[mainindex] != slic.Labels[index] {
-              np++
-            }
-          }
-        }
-      }
-      if np > 1 {
-        contourx[cind] = k
-        contoury[cind] = j
-        istaken[mainindex] = true
-        cind++
-      }
-      mainindex++
-    }
-  }
-
-  for j := 0; j < cind; j++ {
-    x := contourx[j]
-    y := contoury[j]
-    canvas.Set(x, y, black)
+  origins = origins[:label]
+  if slic.merge == MergeClosestColor {
+    return slic.mergeClosestColor(label, nlabels, origins, slic.minSize(SUPSZ))
   }
-
-  return canvas
+  return label, nlabels, origins
 }
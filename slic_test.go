@@ -0,0 +1,510 @@
+package slic
+
+import (
+  "context"
+  "image"
+  "image/color"
+  "math"
+  "math/rand"
+  "testing"
+
+  . "github.com/franela/goblin"
+  "github.com/kurige/SLIC/colorspace"
+  "github.com/kurige/SLIC/lab"
+)
+
+// testImage returns a deterministic image of noisy color gradients.
+func testImage(w, h int) *image.RGBA {
+  rng := rand.New(rand.NewSource(1))
+  img := image.NewRGBA(image.Rect(0, 0, w, h))
+  for y := 0; y < h; y++ {
+    for x := 0; x < w; x++ {
+      noise := rng.Intn(24)
+      img.SetRGBA(x, y, color.RGBA{
+        uint8(255 * x / w),
+        uint8(255*y/h/2 + noise),
+        uint8((x*y)%200 + noise),
+        255,
+      })
+    }
+  }
+  return img
+}
+
+// halves returns a SLIC over a 6x4 image with its top left corner at origin,
+// labeled 0 in the left three columns and 1 in the right three, and with
+// paint giving the color of each pixel relative to origin.
+func halves(origin image.Point, paint func(x, y int) color.RGBA) (*SLIC, *image.RGBA) {
+  img := image.NewRGBA(image.Rect(0, 0, 6, 4).Add(origin))
+  labels := make([]int, 24)
+  for i := range labels {
+    x, y := i%6, i/6
+    labels[i] = x / 3
+    img.SetRGBA(origin.X+x, origin.Y+y, paint(x, y))
+  }
+  s, _ := MakeSlicFromLabels(img, 20, labels)
+  return s, img
+}
+
+func TestImage32(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("Float32 Lab image", func() {
+    g.It("Labels nearly every pixel the same as float64", func() {
+      img := testImage(160, 120)
+
+      s64 := MakeSlic(img, 20, 100)
+      s64.Run(10)
+      s32 := MakeSlicFromLab(lab.ImageToLab32(img), 20, 100)
+      s32.Run(10)
+
+      same := 0
+      for i := range s64.Labels {
+        if s64.Labels[i] == s32.Labels[i] {
+          same++
+        }
+      }
+      g.Assert(float64(same)/float64(len(s64.Labels)) > 0.99).IsTrue()
+      g.Assert(s32.labelCount - s64.labelCount <= 2).IsTrue()
+      g.Assert(s64.labelCount - s32.labelCount <= 2).IsTrue()
+    })
+    g.It("Accepts images not anchored at the origin", func() {
+      img := testImage(160, 120)
+      sub := lab.ImageToLab32(img).SubImage(image.Rect(40, 30, 160, 120)).(*lab.Image32)
+
+      s := MakeSlicFromLab(sub, 20, 100)
+      s.Run(10)
+      g.Assert(len(s.Labels)).Equal(120 * 90)
+      for _, l := range s.Labels {
+        g.Assert(l >= 0 && l < s.labelCount).IsTrue()
+      }
+    })
+  })
+}
+
+func TestColorSpaces(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("Color spaces", func() {
+    g.It("Give the same labels for explicit Lab", func() {
+      img := testImage(160, 120)
+      s := MakeSlic(img, 20, 100)
+      s.Run(10)
+      sl := MakeSlicWithOptions(img, 20, 100, Options{Space: colorspace.LabSpace})
+      sl.Run(10)
+      same := 0
+      for i := range s.Labels {
+        if s.Labels[i] == sl.Labels[i] {
+          same++
+        }
+      }
+      g.Assert(float64(same)/float64(len(s.Labels)) > 0.99).IsTrue()
+    })
+    g.It("Segment in every space", func() {
+      img := testImage(160, 120)
+      for _, space := range colorspace.Spaces {
+        s := MakeSlicWithOptions(img, 20, 100, Options{Space: space})
+        s.Run(10)
+        g.Assert(s.labelCount > 50 && s.labelCount < 300).IsTrue()
+        mean := s.MeanColorImage()
+        r, _, _, _ := mean.At(150, 60).RGBA()
+        g.Assert(r>>8 > 200).IsTrue()
+      }
+    })
+  })
+}
+
+func TestOptions(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("Options", func() {
+    g.It("Offsets every other row for hex seeding", func() {
+      img := testImage(160, 120)
+      grid := MakeSlic(img, 20, 100)
+      hex := MakeSlicWithOptions(img, 20, 100, Options{Seeding: SeedHex})
+      g.Assert(len(hex.Superpixels)).Equal(len(grid.Superpixels))
+      g.Assert(hex.Superpixels[0].X).Equal(grid.Superpixels[0].X)
+      row := grid.XStrips
+      g.Assert(hex.Superpixels[row].X - grid.Superpixels[row].X).Equal(float64(grid.step / 2))
+    })
+    g.It("Moves perturbed seeds by at most one pixel", func() {
+      img := testImage(160, 120)
+      grid := MakeSlic(img, 20, 100)
+      perturbed := MakeSlicWithOptions(img, 20, 100, Options{Seeding: SeedPerturbed})
+      moved := 0
+      for n, s := range perturbed.Superpixels {
+        dx, dy := s.X-grid.Superpixels[n].X, s.Y-grid.Superpixels[n].Y
+        g.Assert(dx >= -1 && dx <= 1 && dy >= -1 && dy <= 1).IsTrue()
+        if dx != 0 || dy != 0 {
+          moved++
+        }
+      }
+      g.Assert(moved > 0).IsTrue()
+    })
+    g.It("Segments with the SLICO distance", func() {
+      s := MakeSlicWithOptions(testImage(160, 120), 20, 100, Options{Distance: DistanceSLICO})
+      s.Run(10)
+      g.Assert(s.LabelCount() > 50 && s.LabelCount() <= len(s.Superpixels)).IsTrue()
+      for _, l := range s.Labels {
+        g.Assert(l >= 0 && l < s.LabelCount()).IsTrue()
+      }
+    })
+    g.It("Stops early once converged", func() {
+      img := testImage(160, 120)
+      fixed := MakeSlic(img, 20, 100)
+      fixed.Run(50)
+      g.Assert(fixed.Iterations).Equal(50)
+      converged := MakeSlicWithOptions(img, 20, 100, Options{Convergence: 0.5})
+      converged.Run(50)
+      g.Assert(converged.Iterations < 50).IsTrue()
+    })
+    g.It("Times every phase", func() {
+      s := MakeSlic(testImage(160, 120), 20, 100)
+      s.Run(2)
+      g.Assert(s.Timings.Conversion > 0 && s.Timings.Assignment > 0).IsTrue()
+      g.Assert(s.Timings.Update > 0 && s.Timings.Connectivity > 0).IsTrue()
+    })
+  })
+}
+
+func TestPolygons(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("Polygons", func() {
+    g.It("Traces outer rings and holes", func() {
+      labels := []int{
+        0, 0, 0,
+        0, 1, 0,
+        0, 0, 0,
+      }
+      polys := tracePolygons(labels, 3, 3, 2)
+      g.Assert(len(polys[0].Rings)).Equal(2)
+      g.Assert(polys[0].Rings[0]).Equal([]image.Point{{0, 0}, {3, 0}, {3, 3}, {0, 3}})
+      g.Assert(area(polys[0].Rings[1])).Equal(-2)
+      g.Assert(polys[1].Rings).Equal([][]image.Point{{{1, 1}, {2, 1}, {2, 2}, {1, 2}}})
+    })
+    g.It("Keeps diagonally touching pixels in separate rings", func() {
+      labels := []int{
+        0, 0, 0,
+        0, 1, 0,
+        1, 0, 0,
+      }
+      polys := tracePolygons(labels, 3, 3, 2)
+      g.Assert(len(polys[1].Rings)).Equal(2)
+      for _, r := range polys[1].Rings {
+        g.Assert(area(r)).Equal(2)
+      }
+    })
+    g.It("Covers every superpixel's area", func() {
+      s := MakeSlic(testImage(160, 120), 20, 100)
+      s.Run(10)
+      pixels := make([]int, s.LabelCount())
+      for _, l := range s.Labels {
+        pixels[l]++
+      }
+      for _, p := range s.Polygons() {
+        total := 0
+        for i, r := range p.Rings {
+          g.Assert(i == 0 && area(r) > 0 || i > 0).IsTrue()
+          total += area(r)
+        }
+        g.Assert(total).Equal(2 * pixels[p.Label])
+      }
+    })
+  })
+}
+
+func TestRunContext(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("RunContext", func() {
+    g.It("Stops when the context is cancelled", func() {
+      s := MakeSlic(testImage(160, 120), 20, 100)
+      ctx, cancel := context.WithCancel(context.Background())
+      cancel()
+      g.Assert(s.RunContext(ctx, 10)).Equal(context.Canceled)
+      g.Assert(s.Iterations).Equal(0)
+    })
+    g.It("Matches Run otherwise", func() {
+      img := testImage(160, 120)
+      a := MakeSlic(img, 20, 100)
+      a.Run(5)
+      b := MakeSlic(img, 20, 100)
+      g.Assert(b.RunContext(context.Background(), 5)).Equal(nil)
+      g.Assert(b.Labels).Equal(a.Labels)
+    })
+  })
+}
+
+func TestRelabel(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("Final labels", func() {
+    g.It("Number superpixels in raster order of first pixel", func() {
+      s := MakeSlic(testImage(160, 120), 20, 100)
+      s.Run(10)
+      next := 0
+      for _, l := range s.Labels {
+        g.Assert(l >= 0 && l <= next).IsTrue()
+        if l == next {
+          next++
+        }
+      }
+      g.Assert(next).Equal(s.LabelCount())
+    })
+    g.It("Re-index Superpixels to match", func() {
+      s := MakeSlic(testImage(160, 120), 20, 100)
+      seeds := len(s.Superpixels)
+      s.Run(10)
+      g.Assert(len(s.Superpixels)).Equal(s.LabelCount())
+      g.Assert(len(s.Seeds())).Equal(seeds)
+      for n, sp := range s.Superpixels {
+        g.Assert(sp.label).Equal(n)
+        g.Assert(sp.Seed >= 0 && sp.Seed < seeds).IsTrue()
+      }
+    })
+    g.It("Trace every label back to a seed inside it or nearby", func() {
+      s := MakeSlic(testImage(160, 120), 20, 100)
+      s.Run(10)
+      for label, sp := range s.Superpixels {
+        seed := s.Seeds()[sp.Seed]
+        // Centers move by less than a grid step, so the superpixel has a
+        // pixel within two steps of its seed.
+        near := false
+        for i, l := range s.Labels {
+          dx, dy := i%160-seed.X, i/160-seed.Y
+          if l == label && dx*dx+dy*dy <= 4*s.step*s.step {
+            near = true
+            break
+          }
+        }
+        g.Assert(near).IsTrue()
+      }
+    })
+    g.It("Are the same on every run", func() {
+      a := MakeSlic(testImage(160, 120), 20, 100)
+      a.Run(10)
+      b := MakeSlic(testImage(160, 120), 20, 100)
+      b.Run(10)
+      g.Assert(a.Labels).Equal(b.Labels)
+      for n := range a.Superpixels {
+        g.Assert(a.Superpixels[n].Seed).Equal(b.Superpixels[n].Seed)
+      }
+    })
+  })
+}
+
+func TestSuperpixelsAfterRun(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("Superpixels after Run", func() {
+    g.It("Match the final label map", func() {
+      img := testImage(160, 120)
+      for _, opts := range []Options{{}, {Distance: DistanceSLICO}} {
+        s := MakeSlicWithOptions(img, 20, 100, opts)
+        s.Run(10)
+        n := s.LabelCount()
+        count := make([]int, n)
+        sx, sy := make([]float64, n), make([]float64, n)
+        for i, l := range s.Labels {
+          count[l]++
+          sx[l] += float64(i % 160)
+          sy[l] += float64(i / 160)
+        }
+        lvec, avec, bvec := s.AverageColors()
+        total := 0
+        for l, sp := range s.Superpixels {
+          g.Assert(sp.Count).Equal(count[l])
+          g.Assert(math.Abs(sp.X-sx[l]/float64(count[l])) < 1e-9).IsTrue()
+          g.Assert(math.Abs(sp.Y-sy[l]/float64(count[l])) < 1e-9).IsTrue()
+          g.Assert(math.Abs(sp.L-lvec[l]) < 1e-9).IsTrue()
+          g.Assert(math.Abs(sp.A-avec[l]) < 1e-9).IsTrue()
+          g.Assert(math.Abs(sp.B-bvec[l]) < 1e-9).IsTrue()
+          total += sp.Count
+        }
+        g.Assert(total).Equal(len(s.Labels))
+      }
+    })
+  })
+}
+
+// connected reports whether every label of s forms one region under the given
+// connectivity.
+func connected(s *SLIC, c Connectivity) bool {
+  w := s.image.Bounds().Dx()
+  dx, dy := neighbours(c)
+  seen := make([]bool, len(s.Labels))
+  found := make([]bool, s.LabelCount())
+  for start, label := range s.Labels {
+    if seen[start] {
+      continue
+    }
+    if found[label] {
+      return false
+    }
+    found[label] = true
+    seen[start] = true
+    stack := []int{start}
+    for len(stack) > 0 {
+      i := stack[len(stack)-1]
+      stack = stack[:len(stack)-1]
+      for n := range dx {
+        x, y := i%w+dx[n], i/w+dy[n]
+        j := y*w + x
+        if x >= 0 && x < w && y >= 0 && j < len(s.Labels) && !seen[j] && s.Labels[j] == label {
+          seen[j] = true
+          stack = append(stack, j)
+        }
+      }
+    }
+  }
+  return true
+}
+
+func TestConnectivity(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("Connectivity enforcement", func() {
+    img := testImage(160, 120)
+    g.It("Keeps superpixels 4- or 8-connected", func() {
+      for _, c := range []Connectivity{Connect4, Connect8} {
+        s := MakeSlicWithOptions(img, 20, 100, Options{Connectivity: c})
+        s.Run(10)
+        g.Assert(connected(s, c)).IsTrue()
+      }
+    })
+    g.It("Merges fragments below the minimum size", func() {
+      for _, merge := range []Merge{MergeAdjacent, MergeClosestColor} {
+        for _, opts := range []Options{{MinSize: 60}, {MinSizeRatio: 0.5}} {
+          opts.Merge = merge
+          s := MakeSlicWithOptions(img, 20, 100, opts)
+          s.Run(10)
+          least := opts.MinSize
+          if least == 0 {
+            least = 50
+          }
+          for _, sp := range s.Superpixels {
+            g.Assert(sp.Count >= least).IsTrue()
+          }
+        }
+      }
+    })
+    g.It("Keeps every fragment with a negative minimum size", func() {
+      def := MakeSlic(img, 20, 100)
+      def.Run(10)
+      all := MakeSlicWithOptions(img, 20, 100, Options{MinSize: -1})
+      all.Run(10)
+      g.Assert(all.LabelCount() > def.LabelCount()).IsTrue()
+      g.Assert(connected(all, Connect4)).IsTrue()
+    })
+    g.It("Merges into the neighbour with the closest color", func() {
+      // Red on the left and blue on the right, with a blue fragment of a
+      // third cluster just across the boundary.
+      bicolor := image.NewRGBA(image.Rect(0, 0, 30, 10))
+      for y := 0; y < 10; y++ {
+        for x := 0; x < 30; x++ {
+          if x < 15 {
+            bicolor.SetRGBA(x, y, color.RGBA{255, 0, 0, 255})
+          } else {
+            bicolor.SetRGBA(x, y, color.RGBA{0, 0, 255, 255})
+          }
+        }
+      }
+      split := func(merge Merge) []int {
+        s := MakeSlicWithOptions(bicolor, 20, 150, Options{Merge: merge})
+        for i := range s.Labels {
+          x, y := i%30, i/30
+          switch {
+          case x < 15:
+            s.Labels[i] = 0
+          case x < 17 && y < 2:
+            s.Labels[i] = 2
+          default:
+            s.Labels[i] = 1
+          }
+        }
+        n, labels, _ := s.enforceLabelConnectivity()
+        g.Assert(n).Equal(2)
+        return labels
+      }
+      g.Assert(split(MergeAdjacent)[15]).Equal(0)
+      g.Assert(split(MergeClosestColor)[15]).Equal(1)
+    })
+    g.It("Numbers closest color merges in raster order", func() {
+      s := MakeSlicWithOptions(img, 20, 100, Options{Merge: MergeClosestColor})
+      s.Run(10)
+      next := 0
+      for _, l := range s.Labels {
+        g.Assert(l <= next).IsTrue()
+        if l == next {
+          next++
+        }
+      }
+      g.Assert(next).Equal(s.LabelCount())
+      g.Assert(connected(s, Connect4)).IsTrue()
+    })
+  })
+}
+
+func TestMakeSlicFromLabels(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("MakeSlicFromLabels", func() {
+    img := testImage(4, 2)
+    g.It("Renumbers sparse labels and keeps unlabeled pixels", func() {
+      s, err := MakeSlicFromLabels(img, 20, []int{7, 7, -1, 42, 7, 7, 42, 42})
+      g.Assert(err).Equal(nil)
+      g.Assert(s.Labels).Equal([]int{0, 0, -1, 1, 0, 0, 1, 1})
+      g.Assert(len(s.Superpixels)).Equal(2)
+      g.Assert(s.LabelCount()).Equal(2)
+      g.Assert(len(s.Seeds())).Equal(2)
+    })
+    g.It("Rejects label maps of the wrong size or without labels", func() {
+      _, err := MakeSlicFromLabels(img, 20, make([]int, 7))
+      g.Assert(err).Equal(ErrLabelCount)
+      _, err = MakeSlicFromLabels(img, 20, []int{-1, -1, -1, -1, -1, -1, -1, -1})
+      g.Assert(err).Equal(ErrNoLabels)
+    })
+    g.It("Computes centroids from the label map", func() {
+      s, _ := MakeSlicFromLabels(img, 20, []int{7, 7, -1, 42, 7, 7, 42, 42})
+      g.Assert(s.Superpixels[0].X).Equal(0.5)
+      g.Assert(s.Superpixels[0].Y).Equal(0.5)
+      g.Assert(math.Abs(s.Superpixels[1].X-8.0/3) < 1e-9).IsTrue()
+      g.Assert(math.Abs(s.Superpixels[1].Y-2.0/3) < 1e-9).IsTrue()
+      limg := lab.ImageToLab(img)
+      var sum float64
+      for _, p := range []image.Point{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+        sum += limg.LabAt(p.X, p.Y).L
+      }
+      g.Assert(math.Abs(s.Superpixels[0].L-sum/4) < 1e-9).IsTrue()
+    })
+    g.It("Refines the segmentation it was given", func() {
+      // Flat 20x20 tiles offset by 4 pixels from 20x20 starting blocks.
+      tiles := image.NewRGBA(image.Rect(0, 0, 160, 120))
+      tile := func(x, y int) int { return (y+4)/20%6*8 + (x+4)/20%8 }
+      for y := 0; y < 120; y++ {
+        for x := 0; x < 160; x++ {
+          bx, by := (x+4)/20, (y+4)/20
+          tiles.SetRGBA(x, y, color.RGBA{uint8(bx * 30), uint8(by * 40), uint8((bx + by) % 2 * 200), 255})
+        }
+      }
+      blocks := make([]int, 160*120)
+      before := 0
+      for i := range blocks {
+        x, y := i%160, i/160
+        blocks[i] = y/20*8 + x/20
+        if blocks[i] != tile(x, y) {
+          before++
+        }
+      }
+
+      s, err := MakeSlicFromLabels(tiles, 20, blocks)
+      g.Assert(err).Equal(nil)
+      g.Assert(len(s.Superpixels)).Equal(48)
+      for n, seed := range s.Seeds() {
+        g.Assert(seed).Equal(image.Pt(n%8*20+10, n/8*20+10))
+      }
+      s.Run(5)
+      g.Assert(s.LabelCount()).Equal(48)
+      after := 0
+      for i, l := range s.Labels {
+        if s.Superpixels[l].Seed != tile(i%160, i/160) {
+          after++
+        }
+      }
+      // The boundaries move most of the way to the tile edges.
+      g.Assert(after < before/4).IsTrue()
+    })
+  })
+}
@@ -0,0 +1,284 @@
+// Package tiled runs SLIC over images too large to convert to Lab in one
+// piece. The image is cut into overlapping tiles that are segmented one at a
+// time; superpixels that cross a seam between tiles are joined into a single
+// label, and the global label map is streamed out rather than kept in memory.
+//
+// Only one row of tiles is converted to Lab at any time. The source image is
+// read through the image.Image interface, so it may be an implementation that
+// decodes pixels on demand.
+package tiled
+
+import (
+  "bufio"
+  "errors"
+  "image"
+  "io"
+  "math"
+  "os"
+
+  "github.com/kurige/SLIC"
+  "github.com/kurige/SLIC/labelio"
+)
+
+var ErrOptions = errors.New("tiled: invalid options")
+
+type Options struct {
+  // TileSize is the width and height of the region each tile contributes to
+  // the label map. Defaults to 1024.
+  TileSize int
+  // Overlap is the extra context, in pixels, each tile is segmented with on
+  // every side. Superpixels are matched across seams within this band.
+  // Defaults to twice the superpixel grid step.
+  Overlap        int
+  SuperPixelSize int
+  Compactness    float64
+  Iterations     int
+  // TempDir holds the intermediate label map; see os.CreateTemp.
+  TempDir string
+}
+
+// Segment writes the label map of src to w in the labelio raw format and
+// returns the number of labels. Labels are numbered 0..n-1 in raster order of
+// first appearance.
+func Segment(src image.Image, w io.Writer, opts Options) (int, error) {
+  if opts.SuperPixelSize <= 0 || opts.Compactness <= 0 {
+    return 0, ErrOptions
+  }
+  step := int(math.Sqrt(float64(opts.SuperPixelSize)) + 0.5)
+  if opts.TileSize <= 0 {
+    opts.TileSize = 1024
+  }
+  if opts.Overlap <= 0 {
+    opts.Overlap = 2 * step
+  }
+  if opts.TileSize < step {
+    return 0, ErrOptions
+  }
+
+  tmp, err := os.CreateTemp(opts.TempDir, "slic-tiled-*.raw")
+  if err != nil {
+    return 0, err
+  }
+  defer os.Remove(tmp.Name())
+  defer tmp.Close()
+
+  s := &segmenter{src: src, bounds: src.Bounds(), opts: opts}
+  tw := bufio.NewWriter(tmp)
+  if err := s.firstPass(tw); err != nil {
+    return 0, err
+  }
+  if err := tw.Flush(); err != nil {
+    return 0, err
+  }
+  if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+    return 0, err
+  }
+  return s.secondPass(bufio.NewReader(tmp), w)
+}
+
+type segmenter struct {
+  src    image.Image
+  bounds image.Rectangle
+  opts   Options
+  sets   unionFind
+}
+
+// tile holds the global provisional labels of one segmented tile. Rect is the
+// tile's segmented area, including overlap, relative to the image origin.
+type tile struct {
+  rect   image.Rectangle
+  labels []int
+}
+
+func (t *tile) at(x, y int) int {
+  return t.labels[(y-t.rect.Min.Y)*t.rect.Dx()+(x-t.rect.Min.X)]
+}
+
+// band holds provisional labels for a range of full-width rows.
+type band struct {
+  y0, y1 int
+  labels []int
+}
+
+// firstPass segments the image one row of tiles at a time, writing
+// provisional labels and recording which of them belong together.
+func (s *segmenter) firstPass(w io.Writer) error {
+  var (
+    width  = s.bounds.Dx()
+    height = s.bounds.Dy()
+    size   = s.opts.TileSize
+    ov     = s.opts.Overlap
+    nx     = (width + size - 1) / size
+    prev   *band
+  )
+
+  row := make([]int, width)
+  for y0 := 0; y0 < height; y0 += size {
+    y1 := min(y0+size, height)
+
+    tiles := make([]*tile, nx)
+    for tx := range tiles {
+      x0 := tx * size
+      x1 := min(x0+size, width)
+      tiles[tx] = s.segment(image.Rect(max(0, x0-ov), max(0, y0-ov), min(width, x1+ov), min(height, y1+ov)))
+    }
+
+    // Seams between neighbouring tiles in this row.
+    for tx := 1; tx < nx; tx++ {
+      a, b := tiles[tx-1], tiles[tx]
+      counts := make(map[[2]int]int)
+      for y := y0; y < y1; y++ {
+        for x := b.rect.Min.X; x < a.rect.Max.X; x++ {
+          counts[[2]int{a.at(x, y), b.at(x, y)}]++
+        }
+      }
+      s.join(counts)
+    }
+
+    // The seam with the row of tiles above.
+    if prev != nil {
+      counts := make(map[[2]int]int)
+      for y := prev.y0; y < prev.y1; y++ {
+        for x := 0; x < width; x++ {
+          upper := prev.labels[(y-prev.y0)*width+x]
+          lower := tiles[x/size].at(x, y)
+          counts[[2]int{upper, lower}]++
+        }
+      }
+      s.join(counts)
+    }
+
+    // Every pixel takes its label from the tile whose core contains it.
+    for y := y0; y < y1; y++ {
+      for x := 0; x < width; x++ {
+        row[x] = tiles[x/size].at(x, y)
+      }
+      if err := labelio.WriteRawLabels(w, row); err != nil {
+        return err
+      }
+    }
+
+    if y1 < height {
+      prev = &band{y0: max(0, y1-ov), y1: min(height, y1+ov)}
+      prev.labels = make([]int, (prev.y1-prev.y0)*width)
+      for y := prev.y0; y < prev.y1; y++ {
+        for x := 0; x < width; x++ {
+          prev.labels[(y-prev.y0)*width+x] = tiles[x/size].at(x, y)
+        }
+      }
+    }
+  }
+
+  return nil
+}
+
+// secondPass rewrites the provisional labels as final, contiguous labels.
+func (s *segmenter) secondPass(r io.Reader, w io.Writer) (int, error) {
+  width, height := s.bounds.Dx(), s.bounds.Dy()
+
+  final := make([]int, len(s.sets))
+  for i := range final {
+    final[i] = -1
+  }
+  count := 0
+
+  bw := bufio.NewWriter(w)
+  if err := labelio.WriteRawHeader(bw, width, height); err != nil {
+    return 0, err
+  }
+  row := make([]int, width)
+  for y := 0; y < height; y++ {
+    if err := labelio.ReadRawLabels(r, row); err != nil {
+      return 0, err
+    }
+    for x, l := range row {
+      root := s.sets.find(l)
+      if final[root] == -1 {
+        final[root] = count
+        count++
+      }
+      row[x] = final[root]
+    }
+    if err := labelio.WriteRawLabels(bw, row); err != nil {
+      return 0, err
+    }
+  }
+  return count, bw.Flush()
+}
+
+// segment runs SLIC over r, relative to the image origin, and returns its
+// labels offset into a range of provisional labels no other tile uses.
+func (s *segmenter) segment(r image.Rectangle) *tile {
+  sl := slic.MakeSlic(s.subImage(r.Add(s.bounds.Min)), s.opts.Compactness, s.opts.SuperPixelSize)
+  sl.Run(s.opts.Iterations)
+
+  base := len(s.sets)
+  n := 0
+  labels := make([]int, len(sl.Labels))
+  for i, l := range sl.Labels {
+    labels[i] = base + l
+    if l+1 > n {
+      n = l + 1
+    }
+  }
+  s.sets.grow(n)
+
+  return &tile{r, labels}
+}
+
+// join merges pairs of labels from either side of a seam that mostly cover
+// the same pixels of the overlap between them.
+func (s *segmenter) join(counts map[[2]int]int) {
+  totals := make(map[int]int)
+  for pair, n := range counts {
+    totals[pair[0]] += n
+    totals[pair[1]] += n
+  }
+  for pair, n := range counts {
+    if 2*n > totals[pair[0]] && 2*n > totals[pair[1]] {
+      s.sets.union(pair[0], pair[1])
+    }
+  }
+}
+
+func (s *segmenter) subImage(r image.Rectangle) image.Image {
+  if sub, ok := s.src.(interface {
+    SubImage(image.Rectangle) image.Image
+  }); ok {
+    return sub.SubImage(r)
+  }
+  return window{s.src, r}
+}
+
+// window restricts an image without a SubImage method to a rectangle.
+type window struct {
+  image.Image
+  rect image.Rectangle
+}
+
+func (w window) Bounds() image.Rectangle { return w.rect }
+
+type unionFind []int
+
+func (u *unionFind) grow(n int) {
+  for i := 0; i < n; i++ {
+    *u = append(*u, len(*u))
+  }
+}
+
+func (u unionFind) find(i int) int {
+  for u[i] != i {
+    u[i] = u[u[i]]
+    i = u[i]
+  }
+  return i
+}
+
+func (u unionFind) union(i, j int) {
+  i, j = u.find(i), u.find(j)
+  if i < j {
+    u[j] = i
+  } else if j < i {
+    u[i] = j
+  }
+}
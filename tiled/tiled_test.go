@@ -0,0 +1,114 @@
+package tiled
+
+import (
+  "bytes"
+  "image"
+  "image/color"
+  "testing"
+
+  . "github.com/franela/goblin"
+  "github.com/kurige/SLIC"
+  "github.com/kurige/SLIC/labelio"
+)
+
+const (
+  WIDTH       = 150
+  HEIGHT      = 110
+  SIZE        = 100
+  COMPACTNESS = 20.0
+  ITERATIONS  = 5
+)
+
+// blocks is an image of large flat rectangles, so superpixel boundaries
+// inside each rectangle are decided by compactness alone.
+func blocks() *image.RGBA {
+  img := image.NewRGBA(image.Rect(-20, 10, WIDTH-20, HEIGHT+10))
+  b := img.Bounds()
+  for y := b.Min.Y; y < b.Max.Y; y++ {
+    for x := b.Min.X; x < b.Max.X; x++ {
+      c := color.RGBA{uint8(x * 5), 120, uint8(200 - y), 255}
+      if (x/37+y/29)%2 == 0 {
+        c = color.RGBA{20, uint8(y), 200, 255}
+      }
+      img.SetRGBA(x, y, c)
+    }
+  }
+  return img
+}
+
+func segment(img image.Image, opts Options) (int, []int, error) {
+  var buf bytes.Buffer
+  n, err := Segment(img, &buf, opts)
+  if err != nil {
+    return 0, nil, err
+  }
+  w, h, labels, err := labelio.ReadRaw(&buf)
+  if err == nil && (w != WIDTH || h != HEIGHT) {
+    err = labelio.ErrDimensions
+  }
+  return n, labels, err
+}
+
+func TestSegment(t *testing.T) {
+  g := Goblin(t)
+  g.Describe("Segment", func() {
+    g.It("Matches plain SLIC when the image fits in one tile", func() {
+      img := blocks()
+      s := slic.MakeSlic(img, COMPACTNESS, SIZE)
+      s.Run(ITERATIONS)
+
+      _, labels, err := segment(img, Options{
+        TileSize:       WIDTH,
+        SuperPixelSize: SIZE,
+        Compactness:    COMPACTNESS,
+        Iterations:     ITERATIONS,
+      })
+      g.Assert(err).Equal(nil)
+      g.Assert(labels).Equal(s.Labels)
+    })
+    g.It("Produces contiguous labels across tiles", func() {
+      n, labels, err := segment(blocks(), Options{
+        TileSize:       40,
+        SuperPixelSize: SIZE,
+        Compactness:    COMPACTNESS,
+        Iterations:     ITERATIONS,
+      })
+      g.Assert(err).Equal(nil)
+
+      seen := make([]bool, n)
+      for _, l := range labels {
+        g.Assert(l >= 0 && l < n).IsTrue()
+        seen[l] = true
+      }
+      for _, ok := range seen {
+        g.Assert(ok).IsTrue()
+      }
+    })
+    g.It("Joins superpixels across seams", func() {
+      opts := Options{
+        TileSize:       40,
+        SuperPixelSize: SIZE,
+        Compactness:    COMPACTNESS,
+        Iterations:     ITERATIONS,
+      }
+      n, _, err := segment(blocks(), opts)
+      g.Assert(err).Equal(nil)
+
+      // Without joining, every tile contributes its own superpixels and the
+      // 4x3 tiles give far more labels than one pass over the image.
+      s := slic.MakeSlic(blocks(), COMPACTNESS, SIZE)
+      s.Run(ITERATIONS)
+      single := 0
+      for _, l := range s.Labels {
+        if l+1 > single {
+          single = l + 1
+        }
+      }
+      g.Assert(n < 5*single/4).IsTrue()
+    })
+    g.It("Rejects missing parameters", func() {
+      _, err := Segment(blocks(), &bytes.Buffer{}, Options{})
+      g.Assert(err).Equal(ErrOptions)
+    })
+  })
+}